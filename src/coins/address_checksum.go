@@ -0,0 +1,80 @@
+package coins
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"wallet-sdk/src/errors"
+	"wallet-sdk/src/types"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ChecksumAddressGenerator is implemented by evm coins, GenerateAddress keeps returning lowercase addresses
+type ChecksumAddressGenerator interface {
+	// GenerateChecksumAddress returns the EIP-55 address, or the EIP-1191 one on chains using it
+	GenerateChecksumAddress(privateKey types.PrivateKey, testNet bool) (*types.CoinAddress, error)
+}
+
+// eip1191ChainIds are the chains whose addresses carry an EIP-1191 checksum
+var eip1191ChainIds = make(map[string]bool, 0)
+
+// ValidateAddress checks an evm address: 20 hex bytes, and the EIP-55 checksum when it is mixed case
+func ValidateAddress(address string) error {
+	return validateChecksumAddress(address, nil)
+}
+
+// ValidateAddressOnChain is ValidateAddress for the chain chainId, mixed case addresses carry the
+// EIP-1191 checksum of chainId on chains using it and the EIP-55 checksum elsewhere
+func ValidateAddressOnChain(address string, chainId *big.Int) error {
+	return validateChecksumAddress(address, checksumChainId(chainId))
+}
+
+// checksumChainId is the chain id of the EIP-1191 checksums of chainId, nil on chains using EIP-55
+func checksumChainId(chainId *big.Int) *big.Int {
+	if chainId == nil || !eip1191ChainIds[chainId.String()] {
+		return nil
+	}
+	return chainId
+}
+
+// validateChecksumAddress is ValidateAddress with the EIP-1191 checksum of chainId when not nil
+func validateChecksumAddress(address string, chainId *big.Int) error {
+	hexAddress := strings.TrimPrefix(address, "0x")
+	addressBytes, err := hex.DecodeString(hexAddress)
+	if err != nil || len(addressBytes) != 20 {
+		return errors.ErrorInvalidAddress
+	}
+	if hexAddress == strings.ToLower(hexAddress) || hexAddress == strings.ToUpper(hexAddress) {
+		return nil
+	}
+	if checksum, _ := ToChecksumAddress(address, chainId); checksum != "0x"+hexAddress {
+		return errors.ErrorInvalidChecksum
+	}
+	return nil
+}
+
+// ToChecksumAddress returns address with the EIP-55 checksum, or with the EIP-1191 checksum of chainId when not nil
+func ToChecksumAddress(address string, chainId *big.Int) (string, error) {
+	lower := strings.ToLower(strings.TrimPrefix(address, "0x"))
+	if addressBytes, err := hex.DecodeString(lower); err != nil || len(addressBytes) != 20 {
+		return "", errors.ErrorInvalidAddress
+	}
+	hashInput := lower
+	if chainId != nil {
+		hashInput = chainId.String() + "0x" + lower
+	}
+	hash := hex.EncodeToString(crypto.Keccak256([]byte(hashInput)))
+	checksum := []byte(lower)
+	for i, c := range checksum {
+		if c >= 'a' && hash[i] >= '8' {
+			checksum[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(checksum), nil
+}
+
+// registerEip1191ChainId makes ValidateAddressOnChain check the EIP-1191 checksums of chainId
+func registerEip1191ChainId(chainId *big.Int) {
+	eip1191ChainIds[chainId.String()] = true
+}
@@ -7,7 +7,7 @@ import (
 )
 
 const (
-	ARB1_TEST = 421613
+	ARB1_TEST = 421614 // Arbitrum Sepolia
 	ARB1_MAIN = 42161
 )
 
@@ -18,6 +18,7 @@ var coinArb1 Arb1
 func init() {
 	coinArb1 = Arb1{}
 	RegisterEthLikeCoin(coinArb1)
+	registerEvmNetworks(CurrencyArb1, ARB1_MAIN, ARB1_TEST)
 }
 
 type Arb1 struct {
@@ -23,7 +23,7 @@ func (coin Arb1Erc20) GetCurrency() string {
 }
 
 func (coin Arb1Erc20) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
-	return createTokenTransaction(params)
+	return createTokenTransaction(params, NetworkFromTestNet(coin.GetCurrency(), testNet).ChainID)
 
 }
 
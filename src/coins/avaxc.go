@@ -19,6 +19,7 @@ func init() {
 	coinAvaxc = Avaxc{}
 
 	RegisterEthLikeCoin(coinAvaxc)
+	registerEvmNetworks(CurrencyAvaxc, AVAX_MAIN, AVAX_TEST)
 }
 
 type Avaxc struct {
@@ -23,7 +23,7 @@ func (coin AvaxErc20) GetCurrency() string {
 }
 
 func (coin AvaxErc20) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
-	return createTokenTransaction(params)
+	return createTokenTransaction(params, NetworkFromTestNet(coin.GetCurrency(), testNet).ChainID)
 
 }
 
@@ -0,0 +1,256 @@
+package coins
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"wallet-sdk/src/errors"
+	"wallet-sdk/src/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/shopspring/decimal"
+)
+
+// canonical batch contracts, deployed at the same address on most evm chains
+const (
+	Multicall3Address = "0xcA11bde05977b3631167028862bE2a173976CA11"
+	DisperseAddress   = "0xD152f549545093347A162Dce210e7293f1452150"
+)
+
+type BatchPayoutMethod string
+
+const (
+	// BatchPayoutMulticall3 pays native coins with aggregate3Value. Tokens can't go through Multicall3,
+	// it would need an allowance anyone can spend by calling it.
+	BatchPayoutMulticall3 BatchPayoutMethod = "multicall3"
+	// BatchPayoutDisperse pays native coins with disperseEther and tokens with disperseToken
+	BatchPayoutDisperse BatchPayoutMethod = "disperse"
+)
+
+// gas of the batch payout estimation: 21000 + calldata + overhead + transfers * transfer gas
+const (
+	// BatchPayoutNativeTransferGas is a value transfer to a cold account that may not exist yet
+	BatchPayoutNativeTransferGas = 9000 + 2600 + 25000
+	// BatchPayoutTokenTransferGas is a token transfer to a holder without balance
+	BatchPayoutTokenTransferGas = 35000
+	// BatchPayoutOverheadGas is the call of the batch contract, disperseToken also pulls the total with transferFrom
+	BatchPayoutOverheadGas         = 10000
+	BatchPayoutTokenOverheadGas    = 45000
+	BatchPayoutApproveGas          = 60000
+	DefaultBatchPayoutMaxGas       = 10000000
+	DefaultBatchPayoutMaxDataBytes = 100000
+)
+
+const batchPayoutAbi = `[
+{"type":"function","name":"disperseEther","inputs":[{"name":"recipients","type":"address[]"},{"name":"values","type":"uint256[]"}]},
+{"type":"function","name":"disperseToken","inputs":[{"name":"token","type":"address"},{"name":"recipients","type":"address[]"},{"name":"values","type":"uint256[]"}]},
+{"type":"function","name":"aggregate3Value","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"value","type":"uint256"},{"name":"callData","type":"bytes"}]}]},
+{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}]}]`
+
+var batchPayoutMethods abi.ABI
+
+func init() {
+	parsed, err := abi.JSON(strings.NewReader(batchPayoutAbi))
+	if err != nil {
+		panic(err)
+	}
+	batchPayoutMethods = parsed
+	_ = RegisterEvmCallDecoder(batchPayoutAbi)
+}
+
+type PayoutTransfer struct {
+	ToAddress string          `json:"toAddress"`
+	Amount    decimal.Decimal `json:"amount"`
+}
+
+// BatchPayoutParams pays Transfers in as few transactions as the caps allow. EthTxParams gives the nonce
+// and fees of the first transaction, the next ones take the following nonces.
+type BatchPayoutParams struct {
+	EthTxParams
+	Method BatchPayoutMethod `json:"batchMethod"`
+	// Contract is the batch contract, the canonical one of Method when empty
+	Contract string `json:"batchContract"`
+	// ContractAddress is the token paid, native coins are paid when empty
+	ContractAddress string           `json:"commonContractAddress"`
+	TokenDecimal    int64            `json:"commonTokenDecimal"`
+	Transfers       []PayoutTransfer `json:"transfers"`
+	// Allowance is the allowance of the batch contract, an approval of the total comes first when it is lower
+	Allowance types.BigInt `json:"allowance"`
+	// MaxDataBytes and MaxGas cap each batch, the defaults are used when 0
+	MaxDataBytes int    `json:"maxDataBytes"`
+	MaxGas       uint64 `json:"maxGas"`
+	// TransferGas overrides the estimated gas of one transfer when not 0
+	TransferGas uint64 `json:"transferGas"`
+}
+
+// BatchPayoutTx is one transaction of a batch payout, to create with the coin of the chain
+type BatchPayoutTx struct {
+	EthTxParams
+	// Transfers paid by the transaction, none for the approval
+	Transfers []PayoutTransfer `json:"transfers"`
+}
+
+// BuildBatchPayout splits the transfers into batches under the caps and encodes each batch for the chain chainId,
+// GasLimit of every transaction is the estimation
+func BuildBatchPayout(params BatchPayoutParams, chainId *big.Int) ([]BatchPayoutTx, error) {
+	if len(params.Transfers) == 0 {
+		return nil, errors.ErrorInvalidInput
+	}
+	isToken := params.ContractAddress != ""
+	if isToken {
+		if err := ValidateAddressOnChain(params.ContractAddress, chainId); err != nil {
+			return nil, errors.ErrorInvalidContractAddress
+		}
+	}
+	contract := params.Contract
+	switch params.Method {
+	case BatchPayoutMulticall3:
+		if isToken {
+			return nil, fmt.Errorf("%w: tokens can't be paid through multicall3, use disperse", errors.ErrorInvalidInput)
+		}
+		if contract == "" {
+			contract = Multicall3Address
+		}
+	case BatchPayoutDisperse:
+		if contract == "" {
+			contract = DisperseAddress
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown batch method %s", errors.ErrorInvalidInput, params.Method)
+	}
+	if err := ValidateAddressOnChain(contract, chainId); err != nil {
+		return nil, errors.ErrorInvalidContractAddress
+	}
+	decimals := params.TokenDecimal
+	if !isToken {
+		decimals = 18
+	}
+	maxGas, maxDataBytes := params.MaxGas, params.MaxDataBytes
+	if maxGas == 0 {
+		maxGas = DefaultBatchPayoutMaxGas
+	}
+	if maxDataBytes == 0 {
+		maxDataBytes = DefaultBatchPayoutMaxDataBytes
+	}
+
+	total := new(big.Int)
+	for _, transfer := range params.Transfers {
+		if err := ValidateAddressOnChain(transfer.ToAddress, chainId); err != nil {
+			return nil, err
+		}
+		if !transfer.Amount.IsPositive() {
+			return nil, errors.ErrorInvalidAmount
+		}
+		total.Add(total, ToWei(transfer.Amount, decimals))
+	}
+
+	var txs []BatchPayoutTx
+	nonce := params.Nonce
+	if isToken && params.Allowance.Cmp(total) < 0 {
+		data, err := packAbiMethod(batchPayoutMethods.Methods["approve"], common.HexToAddress(contract), total)
+		if err != nil {
+			return nil, err
+		}
+		approval := params.EthTxParams
+		approval.ToAddress = params.ContractAddress
+		approval.Amount = decimal.Zero
+		approval.Data = hexutil.Encode(data)
+		approval.GasLimit = types.BigInt{Int: *new(big.Int).SetUint64(BatchPayoutApproveGas)}
+		txs = append(txs, BatchPayoutTx{EthTxParams: approval})
+		nonce++
+	}
+
+	fits := func(tx *BatchPayoutTx) bool {
+		return tx.GasLimit.Uint64() <= maxGas && (len(tx.Data)-2)/2 <= maxDataBytes
+	}
+	var batchTx *BatchPayoutTx
+	for i := 0; i < len(params.Transfers); {
+		var batch []PayoutTransfer
+		if batchTx != nil {
+			batch = batchTx.Transfers
+		}
+		candidate, err := params.batchTx(contract, decimals, append(batch, params.Transfers[i]))
+		if err != nil {
+			return nil, err
+		}
+		if fits(candidate) {
+			batchTx = candidate
+			i++
+			continue
+		}
+		if batchTx == nil {
+			return nil, fmt.Errorf("%w: one transfer exceeds the batch caps", errors.ErrorInvalidInput)
+		}
+		// the batch is full, the transfer starts the next one
+		batchTx.Nonce = nonce
+		txs = append(txs, *batchTx)
+		nonce++
+		batchTx = nil
+	}
+	batchTx.Nonce = nonce
+	txs = append(txs, *batchTx)
+	return txs, nil
+}
+
+// EstimateBatchPayoutGas estimates the gas of a batch payout call with data paying transfers,
+// transferGas is the gas of one transfer
+func EstimateBatchPayoutGas(data []byte, transfers int, overheadGas uint64, transferGas uint64) uint64 {
+	return IntrinsicGas(data, nil, false) + overheadGas + uint64(transfers)*transferGas
+}
+
+// batchTx encodes one batch paying transfers
+func (params BatchPayoutParams) batchTx(contract string, decimals int64, transfers []PayoutTransfer) (*BatchPayoutTx, error) {
+	recipients := make([]common.Address, len(transfers))
+	values := make([]*big.Int, len(transfers))
+	total := new(big.Int)
+	for i, transfer := range transfers {
+		recipients[i] = common.HexToAddress(transfer.ToAddress)
+		values[i] = ToWei(transfer.Amount, decimals)
+		total.Add(total, values[i])
+	}
+
+	var data []byte
+	var err error
+	overheadGas, transferGas := uint64(BatchPayoutOverheadGas), uint64(BatchPayoutNativeTransferGas)
+	switch {
+	case params.ContractAddress != "":
+		overheadGas, transferGas = BatchPayoutTokenOverheadGas, BatchPayoutTokenTransferGas
+		data, err = packAbiMethod(batchPayoutMethods.Methods["disperseToken"], common.HexToAddress(params.ContractAddress), recipients, values)
+	case params.Method == BatchPayoutDisperse:
+		data, err = packAbiMethod(batchPayoutMethods.Methods["disperseEther"], recipients, values)
+	default:
+		// a failed call must revert the batch, Multicall3 would keep its value
+		calls := make([]multicall3Call, len(transfers))
+		for i := range transfers {
+			calls[i] = multicall3Call{Target: recipients[i], Value: values[i], CallData: []byte{}}
+		}
+		data, err = packAbiMethod(batchPayoutMethods.Methods["aggregate3Value"], calls)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if params.TransferGas != 0 {
+		transferGas = params.TransferGas
+	}
+
+	txParams := params.EthTxParams
+	txParams.ToAddress = contract
+	txParams.Amount = decimal.Zero
+	if params.ContractAddress == "" {
+		txParams.Amount = decimal.NewFromBigInt(total, -18)
+	}
+	txParams.Data = hexutil.Encode(data)
+	gas := EstimateBatchPayoutGas(data, len(transfers), overheadGas, transferGas)
+	txParams.GasLimit = types.BigInt{Int: *new(big.Int).SetUint64(gas)}
+	return &BatchPayoutTx{EthTxParams: txParams, Transfers: transfers}, nil
+}
+
+// multicall3Call is the Call3Value struct of Multicall3
+type multicall3Call struct {
+	Target       common.Address
+	AllowFailure bool
+	Value        *big.Int
+	CallData     []byte
+}
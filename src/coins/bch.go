@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/schancel/cashaddr-converter/cashaddress"
+	"strings"
 	"wallet-sdk/src/deriver"
 	"wallet-sdk/src/errors"
 	"wallet-sdk/src/types"
@@ -26,10 +27,28 @@ import (
 	"github.com/ltcsuite/ltcd/ltcutil"
 	"github.com/schancel/cashaddr-converter/address"
 	"github.com/schancel/cashaddr-converter/legacy"
+	"github.com/shopspring/decimal"
 )
 
 const CurrencyBch = "BCH"
 
+// signature algorithms for p2pkh inputs
+const (
+	BchSignatureSchnorr = "schnorr"
+	BchSignatureEcdsa   = "ecdsa"
+)
+
+// RedeemP2PKHSchnorrInputSize is the serialize size of a transaction input
+// redeeming a compressed P2PKH output with a 64 byte Schnorr signature.
+// It is calculated as:
+//
+//   - 32 bytes previous tx
+//   - 4 bytes output index
+//   - 1 byte compact int encoding value 100
+//   - 100 bytes signature script, 1+64+1 signature and 1+33 pubkey
+//   - 4 bytes sequence
+const RedeemP2PKHSchnorrInputSize = 32 + 4 + 1 + 1 + 65 + 1 + 33 + 4
+
 var coinBch Bch
 
 func init() {
@@ -127,12 +146,96 @@ func (coin Bch) GetDeriver() deriver.Deriver {
 	return &deriver.Bip39Deriver{}
 }
 
+type BchUnspent struct {
+	Unspent
+	Token *CashToken `json:"token,omitempty"`
+}
+
+type BchReceiver struct {
+	Receiver
+	Token *CashToken `json:"token,omitempty"`
+}
+
+// BchTxParams extends BtcTxParams with CashTokens. Tokens left over from the
+// inputs are returned to TokenChangeAddress, or ChangeAddress when empty.
+// SignatureType selects BchSignatureSchnorr (default) or BchSignatureEcdsa.
+type BchTxParams struct {
+	types.BaseTxParams
+	Unspends           []BchUnspent    `json:"unspends"`
+	Receivers          []BchReceiver   `json:"receivers"`
+	ChangeAddress      string          `json:"changeAddress"`
+	TokenChangeAddress string          `json:"tokenChangeAddress"`
+	Fee                decimal.Decimal `json:"fee"`
+	SignatureType      string          `json:"signatureType"`
+}
+
+// BchAuthoredTx is the unsigned transaction built by Bch.CreateTransaction,
+// along with the signature algorithm its size was estimated for
+type BchAuthoredTx struct {
+	*txauthor.AuthoredTx
+	SignatureType string
+}
+
+// signsWithBchd reports whether bchd's txscript can sign the transaction,
+// it signs p2pkh inputs with Schnorr and has no notion of token prefixes
+func (tx *BchAuthoredTx) signsWithBchd() bool {
+	return tx.SignatureType != BchSignatureEcdsa && !hasCashTokenInputs(tx.AuthoredTx)
+}
+
+func bchAuthoredTx(tx *types.BaseTransaction) (*BchAuthoredTx, error) {
+	switch coinTx := tx.CoinTransaction.(type) {
+	case *BchAuthoredTx:
+		return coinTx, nil
+	case *txauthor.AuthoredTx:
+		return &BchAuthoredTx{AuthoredTx: coinTx, SignatureType: BchSignatureSchnorr}, nil
+	}
+	return nil, errors.ErrorInvalidInput
+}
+
+func redeemP2PKHInputSize(signatureType string) int {
+	if signatureType == BchSignatureEcdsa {
+		return txsizes.RedeemP2PKHInputSize
+	}
+	return RedeemP2PKHSchnorrInputSize
+}
+
+func newBchTxParams(params BtcTxParams) BchTxParams {
+	bchParams := BchTxParams{
+		BaseTxParams:  params.BaseTxParams,
+		ChangeAddress: params.ChangeAddress,
+		Fee:           params.Fee,
+	}
+	for _, unspend := range params.Unspends {
+		bchParams.Unspends = append(bchParams.Unspends, BchUnspent{Unspent: unspend})
+	}
+	for _, receiver := range params.Receivers {
+		bchParams.Receivers = append(bchParams.Receivers, BchReceiver{Receiver: receiver})
+	}
+	return bchParams
+}
+
 func (coin Bch) GetEmptyTransactionParams() types.TxParams {
-	return BtcTxParams{}
+	return BchTxParams{}
 }
 
 func (coin Bch) CreateTransaction(txParams types.TxParams, testNet bool) (*types.BaseTransaction, error) {
-	extraParams := txParams.(BtcTxParams)
+	var extraParams BchTxParams
+	switch txParams := txParams.(type) {
+	case BchTxParams:
+		extraParams = txParams
+	case BtcTxParams:
+		extraParams = newBchTxParams(txParams)
+	default:
+		return nil, errors.ErrorInvalidInput
+	}
+	signatureType := extraParams.SignatureType
+	switch signatureType {
+	case "":
+		signatureType = BchSignatureSchnorr
+	case BchSignatureSchnorr, BchSignatureEcdsa:
+	default:
+		return nil, errors.ErrorInvalidInput
+	}
 	var unspends = extraParams.Unspends
 	var receivers = extraParams.Receivers
 	changeAddress := extraParams.ChangeAddress
@@ -156,7 +259,7 @@ func (coin Bch) CreateTransaction(txParams types.TxParams, testNet bool) (*types
 			Hash:  *hash,
 			Index: unspend.TxOutputN,
 		}, nil)
-		inputaddr, err := bchutil.DecodeAddress(unspend.Address, &params)
+		inputaddr, _, err := decodeBchAddress(unspend.Address, &params)
 		if err != nil {
 			return nil, err
 		}
@@ -164,6 +267,14 @@ func (coin Bch) CreateTransaction(txParams types.TxParams, testNet bool) (*types
 		if err != nil {
 			return nil, err
 		}
+		// the token prefix is part of the spent output, it is needed to sign
+		if unspend.Token != nil {
+			prefix, err := unspend.Token.Prefix()
+			if err != nil {
+				return nil, err
+			}
+			script = append(prefix, script...)
+		}
 		inputScripts = append(inputScripts, script)
 		currentInputs = append(currentInputs, nextInput)
 		currentInputValues = append(currentInputValues, amount)
@@ -174,7 +285,7 @@ func (coin Bch) CreateTransaction(txParams types.TxParams, testNet bool) (*types
 	}
 	var txOut []*wire.TxOut
 	for _, receiver := range receivers {
-		decAddr, err := bchutil.DecodeAddress(receiver.Address, &params)
+		decAddr, tokenAware, err := decodeBchAddress(receiver.Address, &params)
 		if err != nil {
 			return nil, errors.ErrorInvalidAddress
 		}
@@ -187,6 +298,19 @@ func (coin Bch) CreateTransaction(txParams types.TxParams, testNet bool) (*types
 		if err != nil {
 			return nil, err
 		}
+		if receiver.Token != nil {
+			if !tokenAware {
+				return nil, errors.ErrorNotTokenAwareAddress
+			}
+			prefix, err := receiver.Token.Prefix()
+			if err != nil {
+				return nil, err
+			}
+			script = append(prefix, script...)
+			if a == 0 {
+				a = CashTokenDustValue
+			}
+		}
 		txOut = append(txOut, &wire.TxOut{
 			Value:    int64(a),
 			PkScript: script,
@@ -197,34 +321,60 @@ func (coin Bch) CreateTransaction(txParams types.TxParams, testNet bool) (*types
 		return totalAmount, currentInputs, currentInputValues, inputScripts, nil
 	}
 
-	changeSource := func() ([]byte, error) {
-		if changeAddress == "" {
-			return nil, nil
-		} else {
-			changeAddr, err := bchutil.DecodeAddress(changeAddress, &params)
-			if err != nil {
-				return nil, errors.ErrorInvalidAddress
-			}
-			script, err := txscript.PayToAddrScript(changeAddr)
-			if err != nil {
-				return nil, err
+	addressChangeSource := func(changeAddress string) txauthor.ChangeSource {
+		return func() ([]byte, error) {
+			if changeAddress == "" {
+				return nil, nil
+			} else {
+				changeAddr, _, err := decodeBchAddress(changeAddress, &params)
+				if err != nil {
+					return nil, errors.ErrorInvalidAddress
+				}
+				script, err := txscript.PayToAddrScript(changeAddr)
+				if err != nil {
+					return nil, err
+				}
+				return script, nil
 			}
-			return script, nil
 		}
 	}
+	changeSource := addressChangeSource(changeAddress)
 
-	unsignedTransaction, err := coin.NewUnsignedTransaction(currentInputs, txOut, feeAmount, inputSource, changeSource, changeAddress != "")
+	tokenChangeAddress := extraParams.TokenChangeAddress
+	if tokenChangeAddress == "" {
+		tokenChangeAddress = changeAddress
+	}
+	tokenChange, err := tokenChangeOutputs(unspends, receivers, addressChangeSource(tokenChangeAddress))
 	if err != nil {
 		return nil, err
 	}
+	txOut = append(txOut, tokenChange...)
 
-	return &types.BaseTransaction{CoinTransaction: unsignedTransaction}, nil
+	unsignedTransaction, err := coin.newUnsignedTransaction(currentInputs, txOut, feeAmount, inputSource, changeSource, changeAddress != "", redeemP2PKHInputSize(signatureType))
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.BaseTransaction{CoinTransaction: &BchAuthoredTx{
+		AuthoredTx:    unsignedTransaction,
+		SignatureType: signatureType,
+	}}, nil
 }
 
 func (coin Bch) NewUnsignedTransaction(inputs []*wire.TxIn, outputs []*wire.TxOut, relayFeePerKb bchutil.Amount, fetchInputs txauthor.InputSource, fetchChange txauthor.ChangeSource, hasChange bool) (*txauthor.AuthoredTx, error) {
+	return coin.newUnsignedTransaction(inputs, outputs, relayFeePerKb, fetchInputs, fetchChange, hasChange, RedeemP2PKHSchnorrInputSize)
+}
+
+// estimateSerializeSize is txsizes.EstimateSerializeSize with inputs of inputSize bytes
+func estimateSerializeSize(inputCount int, outputs []*wire.TxOut, addChangeOutput bool, inputSize int) int {
+	return txsizes.EstimateSerializeSize(inputCount, outputs, addChangeOutput) +
+		inputCount*(inputSize-txsizes.RedeemP2PKHInputSize)
+}
+
+func (coin Bch) newUnsignedTransaction(inputs []*wire.TxIn, outputs []*wire.TxOut, relayFeePerKb bchutil.Amount, fetchInputs txauthor.InputSource, fetchChange txauthor.ChangeSource, hasChange bool, inputSize int) (*txauthor.AuthoredTx, error) {
 
 	targetAmount := SumOutputValues(outputs)
-	estimatedSize := txsizes.EstimateSerializeSize(len(inputs), outputs, hasChange)
+	estimatedSize := estimateSerializeSize(len(inputs), outputs, hasChange, inputSize)
 	targetFee := txrules.FeeForSerializeSize(relayFeePerKb, estimatedSize)
 	for {
 		inputAmount, inputs, inputValues, scripts, err := fetchInputs(targetAmount + targetFee)
@@ -235,7 +385,7 @@ func (coin Bch) NewUnsignedTransaction(inputs []*wire.TxIn, outputs []*wire.TxOu
 			return nil, errors.ErrorInsufficientFunds
 		}
 
-		maxSignedSize := txsizes.EstimateSerializeSize(len(inputs), outputs, true)
+		maxSignedSize := estimateSerializeSize(len(inputs), outputs, true, inputSize)
 		maxRequiredFee := txrules.FeeForSerializeSize(relayFeePerKb, maxSignedSize)
 		remainingAmount := inputAmount - targetAmount
 		if remainingAmount < maxRequiredFee {
@@ -296,6 +446,12 @@ func (coin Bch) EstimateSize(inputCount int, outputAddrs []string, hasExtraChang
 }
 
 func (coin Bch) EstimateTxSizes(inputCount int, outputAddrs []string, hasExtraChangeAddr bool, testNet chaincfg.Params) int {
+	return coin.EstimateTxSizesWithSignatureType(inputCount, outputAddrs, hasExtraChangeAddr, testNet, BchSignatureSchnorr)
+}
+
+// EstimateTxSizesWithSignatureType estimates the size of a transaction whose p2pkh
+// inputs are signed with signatureType, BchSignatureSchnorr or BchSignatureEcdsa
+func (coin Bch) EstimateTxSizesWithSignatureType(inputCount int, outputAddrs []string, hasExtraChangeAddr bool, testNet chaincfg.Params, signatureType string) int {
 	changeSize := 0
 	outputCount := len(outputAddrs)
 	if hasExtraChangeAddr {
@@ -306,7 +462,7 @@ func (coin Bch) EstimateTxSizes(inputCount int, outputAddrs []string, hasExtraCh
 	//8 additional bytes are for version and locktime
 	return 8 + wire.VarIntSerializeSize(uint64(inputCount)) +
 		wire.VarIntSerializeSize(uint64(outputCount)) +
-		inputCount*txsizes.RedeemP2PKHInputSize +
+		inputCount*redeemP2PKHInputSize(signatureType) +
 		coin.SumOutputSerializeSizesOfChainParams(outputAddrs, testNet) +
 		changeSize
 }
@@ -314,7 +470,7 @@ func (coin Bch) EstimateTxSizes(inputCount int, outputAddrs []string, hasExtraCh
 func (coin Bch) SumOutputSerializeSizesOfChainParams(outputAddrs []string, params chaincfg.Params) int {
 	var sizeSum = 0
 	for _, addr := range outputAddrs {
-		decodeAddress, err := bchutil.DecodeAddress(addr, &params)
+		decodeAddress, _, err := decodeBchAddress(addr, &params)
 		if err != nil {
 			return 0
 		}
@@ -344,7 +500,7 @@ func (coin Bch) DecodeTransaction(hexStr string, testnet bool) (interface{}, err
 	}
 
 	// Create and return the result.
-	txReply := btcjson.TxRawDecodeResult{
+	txReply := BchTxRawDecodeResult{
 		Txid:     mtx.TxHash().String(),
 		Version:  mtx.Version,
 		Locktime: mtx.LockTime,
@@ -385,16 +541,41 @@ func (coin Bch) createVinList(mtx *wire.MsgTx) []btcjson.Vin {
 	return vinList
 }
 
-func (coin Bch) createVoutList(mtx *wire.MsgTx, chainParams *chaincfg.Params, filterAddrMap map[string]struct{}) []btcjson.Vout {
-	voutList := make([]btcjson.Vout, 0, len(mtx.TxOut))
+// BchVout is a btcjson.Vout with the CashTokens data of the output
+type BchVout struct {
+	btcjson.Vout
+	TokenData *CashToken `json:"tokenData,omitempty"`
+}
+
+// BchTxRawDecodeResult is btcjson.TxRawDecodeResult with token aware outputs
+type BchTxRawDecodeResult struct {
+	Txid     string        `json:"txid"`
+	Version  int32         `json:"version"`
+	Locktime uint32        `json:"locktime"`
+	Vin      []btcjson.Vin `json:"vin"`
+	Vout     []BchVout     `json:"vout"`
+}
+
+func (coin Bch) createVoutList(mtx *wire.MsgTx, chainParams *chaincfg.Params, filterAddrMap map[string]struct{}) []BchVout {
+	voutList := make([]BchVout, 0, len(mtx.TxOut))
 	for i, v := range mtx.TxOut {
-		disbuf, _ := txscript.DisasmString(v.PkScript)
+		// outputs with a malformed token prefix are reported with the whole script
+		token, pkScript, err := ParseCashTokenPrefix(v.PkScript)
+		if err != nil {
+			token, pkScript = nil, v.PkScript
+		}
+		disbuf, _ := txscript.DisasmString(pkScript)
 		scriptClass, addrs, reqSigs, _ := txscript.ExtractPkScriptAddrs(
-			v.PkScript, chainParams)
+			pkScript, chainParams)
 		passesFilter := len(filterAddrMap) == 0
 		encodedAddrs := make([]string, len(addrs))
 		for j, addr := range addrs {
 			encodedAddr := addr.EncodeAddress()
+			if token != nil {
+				if tokenAddr, err := tokenAwareAddress(addr, chainParams); err == nil {
+					encodedAddr = strings.TrimPrefix(tokenAddr, chainParams.CashAddressPrefix+":")
+				}
+			}
 			encodedAddrs[j] = encodedAddr
 
 			// No need to check the map again if the filter already
@@ -411,14 +592,15 @@ func (coin Bch) createVoutList(mtx *wire.MsgTx, chainParams *chaincfg.Params, fi
 			continue
 		}
 
-		var vout btcjson.Vout
+		var vout BchVout
 		vout.N = uint32(i)
 		vout.Value = bchutil.Amount(v.Value).ToBCH()
 		vout.ScriptPubKey.Addresses = encodedAddrs
 		vout.ScriptPubKey.Asm = disbuf
-		vout.ScriptPubKey.Hex = hex.EncodeToString(v.PkScript)
+		vout.ScriptPubKey.Hex = hex.EncodeToString(pkScript)
 		vout.ScriptPubKey.Type = scriptClass.String()
 		vout.ScriptPubKey.ReqSigs = int32(reqSigs)
+		vout.TokenData = token
 
 		voutList = append(voutList, vout)
 	}
@@ -427,7 +609,10 @@ func (coin Bch) createVoutList(mtx *wire.MsgTx, chainParams *chaincfg.Params, fi
 }
 
 func (coin Bch) SignTx(baseTransaction *types.BaseTransaction, testNet bool, derivedKey types.PrivateKey) (*string, error) {
-	authoredTx := baseTransaction.CoinTransaction.(*txauthor.AuthoredTx)
+	authoredTx, err := bchAuthoredTx(baseTransaction)
+	if err != nil {
+		return nil, err
+	}
 	//signTransaction, err := btc2.SignTransaction(transaction, privateKey, GetNetParams(testNet), true)
 	netParams := coin.getNetParams(testNet)
 	privateKey, err := crypto.ToECDSA(derivedKey)
@@ -458,21 +643,28 @@ func (coin Bch) SignTx(baseTransaction *types.BaseTransaction, testNet bool, der
 		return txscript.PayToAddrScript(key)
 	})
 
-	for i, txIn := range authoredTx.Tx.TxIn {
-		output, err := txscript.SignTxOutput(&netParams, authoredTx.Tx,
-			i,
-			int64(authoredTx.PrevInputValues[i]),
-			script,
-			txscript.SigHashAll,
-			mkGetKey(),
-			getScript,
-			nil,
-		)
-
-		txIn.SignatureScript = output
+	if !authoredTx.signsWithBchd() {
+		err = signBchP2PKHInputs(authoredTx, &netParams, mkGetKey())
 		if err != nil {
 			return nil, err
 		}
+	} else {
+		for i, txIn := range authoredTx.Tx.TxIn {
+			output, err := txscript.SignTxOutput(&netParams, authoredTx.Tx,
+				i,
+				int64(authoredTx.PrevInputValues[i]),
+				script,
+				txscript.SigHashAll,
+				mkGetKey(),
+				getScript,
+				nil,
+			)
+
+			txIn.SignatureScript = output
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	var buf bytes.Buffer
@@ -492,7 +684,10 @@ func (coin Bch) SignMultipleSendAddressTx(baseTransaction *types.BaseTransaction
 
 func (coin Bch) SignMultipleSendAddress(tx *types.BaseTransaction, keys map[string]types.PrivateKey, testNet bool) (*string, error) {
 	netParams := coin.getNetParams(testNet)
-	authoredTx := tx.CoinTransaction.(*txauthor.AuthoredTx)
+	authoredTx, err := bchAuthoredTx(tx)
+	if err != nil {
+		return nil, err
+	}
 
 	mkGetKey := func() txscript.KeyDB {
 
@@ -564,12 +759,19 @@ func (coin Bch) SignMultipleSendAddress(tx *types.BaseTransaction, keys map[stri
 		return txscript.PayToAddrScript(key)
 	})
 
-	source := BchSecProvider{&netParams,
-		mkGetKey(), getScript,
-	}
-	err := authoredTx.AddAllInputScripts(source)
-	if err != nil {
-		return nil, err
+	if !authoredTx.signsWithBchd() {
+		err = signBchP2PKHInputs(authoredTx, &netParams, mkGetKey())
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		source := BchSecProvider{&netParams,
+			mkGetKey(), getScript,
+		}
+		err = authoredTx.AddAllInputScripts(source)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var buf bytes.Buffer
@@ -582,8 +784,66 @@ func (coin Bch) SignMultipleSendAddress(tx *types.BaseTransaction, keys map[stri
 	return &toString, nil
 }
 
+// signBchP2PKHInputs signs the p2pkh inputs of tx with its signature type.
+// Transactions spending token outputs also commit to all spent outputs with SIGHASH_UTXOS.
+func signBchP2PKHInputs(tx *BchAuthoredTx, params *chaincfg.Params, keyDB txscript.KeyDB) error {
+	prevOuts := make([]*wire.TxOut, len(tx.Tx.TxIn))
+	for i := range tx.Tx.TxIn {
+		prevOuts[i] = wire.NewTxOut(int64(tx.PrevInputValues[i]), tx.PrevScripts[i])
+	}
+	hashType := txscript.SigHashAll | txscript.SigHashForkID
+	if hasCashTokenInputs(tx.AuthoredTx) {
+		hashType |= SigHashUtxos
+	}
+
+	for i, txIn := range tx.Tx.TxIn {
+		_, lockingScript, err := ParseCashTokenPrefix(prevOuts[i].PkScript)
+		if err != nil {
+			return err
+		}
+		class, addrs, _, err := txscript.ExtractPkScriptAddrs(lockingScript, params)
+		if err != nil {
+			return err
+		}
+		if class != txscript.PubKeyHashTy {
+			return fmt.Errorf("can't sign %s input %d", class, i)
+		}
+		key, compressed, err := keyDB.GetKey(addrs[0])
+		if err != nil {
+			return err
+		}
+
+		hash, err := calcBchSignatureHash(tx.Tx, i, lockingScript, hashType, prevOuts)
+		if err != nil {
+			return err
+		}
+		var signature *bchec.Signature
+		if tx.SignatureType == BchSignatureEcdsa {
+			signature, err = key.SignECDSA(hash)
+		} else {
+			signature, err = key.SignSchnorr(hash)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot sign tx input: %s", err)
+		}
+		pk := (*bchec.PublicKey)(&key.PublicKey)
+		pkData := pk.SerializeUncompressed()
+		if compressed {
+			pkData = pk.SerializeCompressed()
+		}
+		txIn.SignatureScript, err = txscript.NewScriptBuilder().
+			AddData(append(signature.Serialize(), byte(hashType))).
+			AddData(pkData).
+			Script()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (coin Bch) GetTransactionParamsFromJson(paramsJson string) types.TxParams {
-	params := BtcTxParams{}
+	params := BchTxParams{}
 	err := json.Unmarshal([]byte(paramsJson), &params)
 	if err != nil {
 		return nil
@@ -0,0 +1,421 @@
+package coins
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"wallet-sdk/src/errors"
+
+	"github.com/gcash/bchd/chaincfg"
+	"github.com/gcash/bchd/chaincfg/chainhash"
+	"github.com/gcash/bchd/txscript"
+	"github.com/gcash/bchd/wire"
+	"github.com/gcash/bchutil"
+	"github.com/gcash/bchwallet/wallet/txauthor"
+	"github.com/schancel/cashaddr-converter/cashaddress"
+)
+
+// https://github.com/cashtokens/cashtokens
+const (
+	cashTokenPrefixByte          = 0xef
+	cashTokenHasCommitmentLength = 0x40
+	cashTokenHasNft              = 0x20
+	cashTokenHasAmount           = 0x10
+	cashTokenReservedBit         = 0x80
+	cashTokenCapabilityMask      = 0x0f
+
+	// MaxCashTokenCommitmentLength is the maximum NFT commitment length in bytes
+	MaxCashTokenCommitmentLength = 40
+	// CashTokenDustValue is the satoshi value attached to token outputs
+	// when the receiver does not provide one
+	CashTokenDustValue = 1000
+
+	// SigHashUtxos commits the signature to every output spent by the transaction
+	SigHashUtxos txscript.SigHashType = 0x20
+
+	// token-aware cashaddr types
+	cashAddressTokenP2KH uint8 = 2
+	cashAddressTokenP2SH uint8 = 3
+)
+
+const (
+	CashTokenCapabilityNone    = "none"
+	CashTokenCapabilityMutable = "mutable"
+	CashTokenCapabilityMinting = "minting"
+)
+
+var cashTokenCapabilities = []string{CashTokenCapabilityNone, CashTokenCapabilityMutable, CashTokenCapabilityMinting}
+
+type CashTokenNft struct {
+	Capability string `json:"capability"`
+	// Commitment is hex encoded, empty for NFTs without commitment
+	Commitment string `json:"commitment"`
+}
+
+type CashToken struct {
+	// Category is the token category id, in the same byte order as a txid string
+	Category string        `json:"category"`
+	Amount   uint64        `json:"amount"`
+	Nft      *CashTokenNft `json:"nft,omitempty"`
+}
+
+// Prefix serializes the token into the prefix placed in front of the locking bytecode
+func (token CashToken) Prefix() ([]byte, error) {
+	category, err := chainhash.NewHashFromStr(token.Category)
+	if err != nil || len(token.Category) != chainhash.MaxHashStringSize {
+		return nil, fmt.Errorf("invalid token category %s", token.Category)
+	}
+	if token.Amount > math.MaxInt64 {
+		return nil, fmt.Errorf("token amount %d exceeds the maximum", token.Amount)
+	}
+	if token.Nft == nil && token.Amount == 0 {
+		return nil, fmt.Errorf("token of category %s carries neither amount nor nft", token.Category)
+	}
+
+	var bitfield byte
+	var commitment []byte
+	if token.Nft != nil {
+		capability := -1
+		for i, c := range cashTokenCapabilities {
+			if c == token.Nft.Capability {
+				capability = i
+			}
+		}
+		if capability < 0 {
+			return nil, fmt.Errorf("invalid nft capability %s", token.Nft.Capability)
+		}
+		if commitment, err = hex.DecodeString(token.Nft.Commitment); err != nil {
+			return nil, fmt.Errorf("invalid nft commitment %s", token.Nft.Commitment)
+		}
+		if len(commitment) > MaxCashTokenCommitmentLength {
+			return nil, fmt.Errorf("nft commitment exceeds %d bytes", MaxCashTokenCommitmentLength)
+		}
+		bitfield |= cashTokenHasNft | byte(capability)
+		if len(commitment) > 0 {
+			bitfield |= cashTokenHasCommitmentLength
+		}
+	}
+	if token.Amount > 0 {
+		bitfield |= cashTokenHasAmount
+	}
+
+	var b bytes.Buffer
+	b.WriteByte(cashTokenPrefixByte)
+	b.Write(category[:])
+	b.WriteByte(bitfield)
+	if len(commitment) > 0 {
+		if err = wire.WriteVarBytes(&b, 0, commitment); err != nil {
+			return nil, err
+		}
+	}
+	if token.Amount > 0 {
+		if err = wire.WriteVarInt(&b, 0, token.Amount); err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// ParseCashTokenPrefix splits an output script into its token data and
+// locking bytecode. The token is nil when the script carries no prefix.
+func ParseCashTokenPrefix(pkScript []byte) (*CashToken, []byte, error) {
+	if len(pkScript) == 0 || pkScript[0] != cashTokenPrefixByte {
+		return nil, pkScript, nil
+	}
+	r := bytes.NewReader(pkScript[1:])
+	var category chainhash.Hash
+	if _, err := r.Read(category[:]); err != nil || r.Len() < 1 {
+		return nil, nil, fmt.Errorf("truncated token prefix")
+	}
+	bitfield, _ := r.ReadByte()
+	capability := int(bitfield & cashTokenCapabilityMask)
+	hasNft := bitfield&cashTokenHasNft != 0
+	switch {
+	case bitfield&cashTokenReservedBit != 0:
+		return nil, nil, fmt.Errorf("token prefix uses reserved bit")
+	case capability >= len(cashTokenCapabilities):
+		return nil, nil, fmt.Errorf("invalid nft capability %d", capability)
+	case !hasNft && (capability != 0 || bitfield&cashTokenHasCommitmentLength != 0):
+		return nil, nil, fmt.Errorf("token prefix has nft fields without nft")
+	case !hasNft && bitfield&cashTokenHasAmount == 0:
+		return nil, nil, fmt.Errorf("token prefix carries neither amount nor nft")
+	}
+
+	token := &CashToken{Category: category.String()}
+	if hasNft {
+		token.Nft = &CashTokenNft{Capability: cashTokenCapabilities[capability]}
+		if bitfield&cashTokenHasCommitmentLength != 0 {
+			commitment, err := wire.ReadVarBytes(r, 0, MaxCashTokenCommitmentLength, "commitment")
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(commitment) == 0 {
+				return nil, nil, fmt.Errorf("token prefix has zero length commitment")
+			}
+			token.Nft.Commitment = hex.EncodeToString(commitment)
+		}
+	}
+	if bitfield&cashTokenHasAmount != 0 {
+		amount, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		if amount == 0 || amount > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("invalid token amount %d", amount)
+		}
+		token.Amount = amount
+	}
+	return token, pkScript[len(pkScript)-r.Len():], nil
+}
+
+// decodeBchAddress decodes cash, token-aware cash and legacy addresses. The
+// returned flag reports whether the address signals token support.
+func decodeBchAddress(addr string, params *chaincfg.Params) (bchutil.Address, bool, error) {
+	cashAddr, err := cashaddress.Decode(addr, params.CashAddressPrefix)
+	if err == nil && cashAddr.Prefix == params.CashAddressPrefix {
+		switch cashAddr.Version {
+		case cashAddressTokenP2KH:
+			decoded, err := bchutil.NewAddressPubKeyHash(cashAddr.Payload, params)
+			return decoded, true, err
+		case cashAddressTokenP2SH:
+			decoded, err := bchutil.NewAddressScriptHashFromHash(cashAddr.Payload, params)
+			return decoded, true, err
+		}
+	}
+	decoded, err := bchutil.DecodeAddress(addr, params)
+	return decoded, false, err
+}
+
+// tokenAwareAddress encodes a p2pkh or p2sh address in its token-aware cashaddr form
+func tokenAwareAddress(addr bchutil.Address, params *chaincfg.Params) (string, error) {
+	cashAddr := cashaddress.Address{Prefix: params.CashAddressPrefix, Payload: addr.ScriptAddress()}
+	switch addr.(type) {
+	case *bchutil.AddressPubKeyHash:
+		cashAddr.Version = cashAddressTokenP2KH
+	case *bchutil.AddressScriptHash:
+		cashAddr.Version = cashAddressTokenP2SH
+	default:
+		return "", errors.ErrorInvalidAddress
+	}
+	return cashAddr.Encode()
+}
+
+// GenerateTokenAddress returns the token-aware (z prefix) cash address of the key
+func (coin Bch) GenerateTokenAddress(keyByte []byte, testNet bool) (string, error) {
+	generated, err := coin.GenerateAddress(keyByte, testNet)
+	if err != nil {
+		return "", err
+	}
+	params := coin.getNetParams(testNet)
+	addr, err := bchutil.DecodeAddress(generated.AddressStr, &params)
+	if err != nil {
+		return "", err
+	}
+	return tokenAwareAddress(addr, &params)
+}
+
+// tokenChangeOutputs checks that the token outputs are covered by the token
+// inputs, and returns the outputs returning unspent tokens to changeScript
+// so that they are not burned.
+func tokenChangeOutputs(unspends []BchUnspent, receivers []BchReceiver, changeScript func() ([]byte, error)) ([]*wire.TxOut, error) {
+	// only outpoints with index 0 can create a new category, named after their txid
+	genesis := map[string]bool{}
+	for _, unspend := range unspends {
+		if unspend.TxOutputN == 0 {
+			genesis[unspend.TxHash] = true
+		}
+	}
+
+	var categories []string
+	amounts := map[string]uint64{}
+	var nfts []*CashToken
+	for _, unspend := range unspends {
+		if unspend.Token == nil {
+			continue
+		}
+		if _, ok := amounts[unspend.Token.Category]; !ok {
+			categories = append(categories, unspend.Token.Category)
+		}
+		amounts[unspend.Token.Category] += unspend.Token.Amount
+		if unspend.Token.Nft != nil {
+			nfts = append(nfts, unspend.Token)
+		}
+	}
+
+	hasCapability := func(category, capability string) bool {
+		for _, nft := range nfts {
+			if nft.Category == category && nft.Nft.Capability == capability {
+				return true
+			}
+		}
+		return false
+	}
+	spend := func(match func(nft *CashToken) bool) bool {
+		for i, nft := range nfts {
+			if match(nft) {
+				nfts = append(nfts[:i:i], nfts[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, receiver := range receivers {
+		token := receiver.Token
+		if token == nil || genesis[token.Category] {
+			continue
+		}
+		if token.Amount > amounts[token.Category] {
+			return nil, errors.ErrorInsufficientTokens
+		}
+		amounts[token.Category] -= token.Amount
+		if token.Nft == nil {
+			continue
+		}
+		// an nft is either moved unchanged, minted from a minting nft or
+		// produced by modifying a mutable nft
+		same := spend(func(nft *CashToken) bool {
+			return nft.Category == token.Category && *nft.Nft == *token.Nft
+		})
+		if same || hasCapability(token.Category, CashTokenCapabilityMinting) {
+			continue
+		}
+		if !spend(func(nft *CashToken) bool {
+			return nft.Category == token.Category && nft.Nft.Capability == CashTokenCapabilityMutable
+		}) {
+			return nil, errors.ErrorInsufficientTokens
+		}
+	}
+
+	var changes []*CashToken
+	for _, category := range categories {
+		amount := amounts[category]
+		for _, nft := range nfts {
+			if nft.Category != category {
+				continue
+			}
+			// the fungible change rides along with the first nft of its category
+			changes = append(changes, &CashToken{Category: category, Amount: amount, Nft: nft.Nft})
+			amount = 0
+		}
+		if amount > 0 {
+			changes = append(changes, &CashToken{Category: category, Amount: amount})
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	script, err := changeScript()
+	if err != nil {
+		return nil, err
+	}
+	if script == nil {
+		return nil, errors.ErrorInvalidAddress
+	}
+	var txOut []*wire.TxOut
+	for _, change := range changes {
+		prefix, err := change.Prefix()
+		if err != nil {
+			return nil, err
+		}
+		txOut = append(txOut, wire.NewTxOut(CashTokenDustValue, append(prefix, script...)))
+	}
+	return txOut, nil
+}
+
+// hasCashTokenInputs reports whether any output spent by the transaction carries tokens
+func hasCashTokenInputs(authoredTx *txauthor.AuthoredTx) bool {
+	for _, script := range authoredTx.PrevScripts {
+		if len(script) > 0 && script[0] == cashTokenPrefixByte {
+			return true
+		}
+	}
+	return false
+}
+
+// calcBchSignatureHash computes the BIP143 style BCH signature hash, extended
+// by CashTokens with the token prefix of the spent output and SIGHASH_UTXOS.
+// prevOuts holds every spent output, token prefix included.
+func calcBchSignatureHash(tx *wire.MsgTx, idx int, subScript []byte, hashType txscript.SigHashType, prevOuts []*wire.TxOut) ([]byte, error) {
+	if idx > len(tx.TxIn)-1 || len(prevOuts) != len(tx.TxIn) {
+		return nil, fmt.Errorf("idx %d but %d txins and %d prevouts", idx, len(tx.TxIn), len(prevOuts))
+	}
+	anyoneCanPay := hashType&txscript.SigHashAnyOneCanPay != 0
+	utxos := hashType&SigHashUtxos != 0
+	if hashType&txscript.SigHashForkID == 0 || (utxos && anyoneCanPay) {
+		return nil, fmt.Errorf("invalid hash type 0x%x", uint32(hashType))
+	}
+	baseType := hashType & 0x1f
+	tokenPrefix := prevOuts[idx].PkScript[:len(prevOuts[idx].PkScript)-len(subScript)]
+
+	var b, sigHash bytes.Buffer
+	var zeroHash chainhash.Hash
+	writeUint32 := func(v uint32) {
+		var buf [4]byte
+		binary.LittleEndian.PutUint32(buf[:], v)
+		sigHash.Write(buf[:])
+	}
+	writeUint32(uint32(tx.Version))
+
+	if anyoneCanPay {
+		sigHash.Write(zeroHash[:])
+	} else {
+		for _, in := range tx.TxIn {
+			b.Write(in.PreviousOutPoint.Hash[:])
+			binary.Write(&b, binary.LittleEndian, in.PreviousOutPoint.Index)
+		}
+		sigHash.Write(chainhash.DoubleHashB(b.Bytes()))
+		b.Reset()
+	}
+	if utxos {
+		for _, prevOut := range prevOuts {
+			if err := wire.WriteTxOut(&b, 0, 0, prevOut); err != nil {
+				return nil, err
+			}
+		}
+		sigHash.Write(chainhash.DoubleHashB(b.Bytes()))
+		b.Reset()
+	}
+	if anyoneCanPay || baseType == txscript.SigHashSingle || baseType == txscript.SigHashNone {
+		sigHash.Write(zeroHash[:])
+	} else {
+		for _, in := range tx.TxIn {
+			binary.Write(&b, binary.LittleEndian, in.Sequence)
+		}
+		sigHash.Write(chainhash.DoubleHashB(b.Bytes()))
+		b.Reset()
+	}
+
+	sigHash.Write(tx.TxIn[idx].PreviousOutPoint.Hash[:])
+	writeUint32(tx.TxIn[idx].PreviousOutPoint.Index)
+	sigHash.Write(tokenPrefix)
+	if err := wire.WriteVarBytes(&sigHash, 0, subScript); err != nil {
+		return nil, err
+	}
+	binary.Write(&sigHash, binary.LittleEndian, prevOuts[idx].Value)
+	writeUint32(tx.TxIn[idx].Sequence)
+
+	switch {
+	case baseType != txscript.SigHashSingle && baseType != txscript.SigHashNone:
+		for _, out := range tx.TxOut {
+			if err := wire.WriteTxOut(&b, 0, 0, out); err != nil {
+				return nil, err
+			}
+		}
+		sigHash.Write(chainhash.DoubleHashB(b.Bytes()))
+	case baseType == txscript.SigHashSingle && idx < len(tx.TxOut):
+		if err := wire.WriteTxOut(&b, 0, 0, tx.TxOut[idx]); err != nil {
+			return nil, err
+		}
+		sigHash.Write(chainhash.DoubleHashB(b.Bytes()))
+	default:
+		sigHash.Write(zeroHash[:])
+	}
+
+	writeUint32(tx.LockTime)
+	writeUint32(uint32(hashType))
+	return chainhash.DoubleHashB(sigHash.Bytes()), nil
+}
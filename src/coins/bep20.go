@@ -23,7 +23,7 @@ func (coin Bep20) GetCurrency() string {
 }
 
 func (coin Bep20) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
-	return createTokenTransaction(params)
+	return createTokenTransaction(params, NetworkFromTestNet(coin.GetCurrency(), testNet).ChainID)
 
 }
 
@@ -87,3 +87,15 @@ func BsvTestNet3Params() chaincfg.Params {
 	params.Bech32HRPSegwit = "dash"
 	return params
 }
+
+// BtcTestNet4Params is testnet3 with the magic and port of BIP94 testnet4, address prefixes are unchanged
+func BtcTestNet4Params() chaincfg.Params {
+
+	var params = chaincfg.TestNet3Params
+	params.Name = "testnet4"
+	params.Net = 0x283f161c
+	params.DefaultPort = "48333"
+	params.DNSSeeds = nil
+	params.Checkpoints = nil
+	return params
+}
@@ -7,6 +7,18 @@ import (
 	"github.com/btcsuite/btcd/txscript"
 )
 
+// omni transaction types, https://github.com/OmniLayer/spec
+const (
+	OmniTypeSimpleSend = 0
+	OmniTypeSendAll    = 4
+)
+
+// omni ecosystems used by send all
+const (
+	OmniEcosystemMain = 1
+	OmniEcosystemTest = 2
+)
+
 func UtilCreatePayloadSimpleSend(propertyID uint, amount float64, divisible bool) (string, error) {
 	var intPart int64
 
@@ -22,13 +34,23 @@ func UtilCreatePayloadSimpleSend(propertyID uint, amount float64, divisible bool
 	return fmt.Sprintf("%016x%016x", propertyID, intPart), nil
 }
 
+func UtilCreatePayloadSendAll(ecosystem uint8) string {
+	return fmt.Sprintf("%04x%04x%02x", 0, OmniTypeSendAll, ecosystem)
+}
+
 func GetClassCOpreturnDataScript(propertyID uint, amount float64, divisible bool) ([]byte, error) {
 	payload, err := UtilCreatePayloadSimpleSend(propertyID, amount, divisible)
 	if err != nil {
 		return nil, err
 	}
+	return getClassCOpreturnScript(payload)
+}
+
+func GetClassCSendAllOpreturnDataScript(ecosystem uint8) ([]byte, error) {
+	return getClassCOpreturnScript(UtilCreatePayloadSendAll(ecosystem))
+}
 
-	fmt.Println(omniHex + payload)
+func getClassCOpreturnScript(payload string) ([]byte, error) {
 	b, err := hex.DecodeString(omniHex + payload)
 
 	if err != nil {
@@ -17,6 +17,7 @@ var coinBnb Bnb
 func init() {
 	coinBnb = Bnb{}
 	RegisterEthLikeCoin(coinBnb)
+	registerEvmNetworks(CurrencyBnb, BNB_MAIN, BNB_TEST)
 }
 
 type Bnb struct {
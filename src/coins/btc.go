@@ -44,6 +44,13 @@ var coinBtc Btc
 func init() {
 	coinBtc = Btc{}
 	RegisterUtxoCoin(coinBtc)
+
+	testNet4Params := BtcTestNet4Params()
+	RegisterNetwork(CurrencyBtc, Network{ID: "mainnet", Kind: NetworkMainNet, ChainParams: &chaincfg.MainNetParams})
+	RegisterNetwork(CurrencyBtc, Network{ID: "testnet3", Kind: NetworkTestNet, ChainParams: &chaincfg.TestNet3Params})
+	RegisterNetwork(CurrencyBtc, Network{ID: "testnet4", Kind: NetworkTestNet, ChainParams: &testNet4Params})
+	RegisterNetwork(CurrencyBtc, Network{ID: "signet", Kind: NetworkTestNet, ChainParams: &chaincfg.SigNetParams})
+	RegisterNetwork(CurrencyBtc, Network{ID: "regtest", Kind: NetworkDevNet, ChainParams: &chaincfg.RegressionNetParams})
 }
 
 type Btc struct {
@@ -94,6 +101,14 @@ func (coin Btc) GenerateAddress(keyByte types.PrivateKey, testNet bool) (*types.
 	return coin.GenAddress(keyByte, netParams)
 }
 
+func (coin Btc) GenerateAddressOnNetwork(keyByte types.PrivateKey, network Network) (*types.CoinAddress, error) {
+	netParams, err := networkChainParams(network)
+	if err != nil {
+		return nil, err
+	}
+	return coin.GenAddress(keyByte, netParams)
+}
+
 func (coin Btc) GenAddress(keyByte []byte, netParams chaincfg.Params) (*types.CoinAddress, error) {
 	_, pubKey := btcec.PrivKeyFromBytes(keyByte)
 
@@ -212,7 +227,19 @@ func (coin Btc) CreateTransaction(txParams types.TxParams, testNet bool) (*types
 	return coin.Transaction(txParams, netParams)
 }
 
+func (coin Btc) CreateTransactionOnNetwork(txParams types.TxParams, network Network) (*types.BaseTransaction, error) {
+	netParams, err := networkChainParams(network)
+	if err != nil {
+		return nil, err
+	}
+	return coin.Transaction(txParams, netParams)
+}
+
 func (coin Btc) Transaction(txParams types.TxParams, netParams chaincfg.Params) (*types.BaseTransaction, error) {
+	return coin.transaction(txParams, netParams, MinNondustOutput)
+}
+
+func (coin Btc) transaction(txParams types.TxParams, netParams chaincfg.Params, dustLimit btcutil.Amount) (*types.BaseTransaction, error) {
 	extraParams := txParams.(BtcTxParams)
 	var unspends = extraParams.Unspends
 	var receivers = extraParams.Receivers
@@ -295,7 +322,7 @@ func (coin Btc) Transaction(txParams types.TxParams, netParams chaincfg.Params)
 		}
 	}
 
-	unsignedTransaction, err := coin.NewUnsignedTransaction(currentInputs, txOut, feeAmount, inputSource, &changeSource, changeAddress != "")
+	unsignedTransaction, err := coin.newUnsignedTransaction(currentInputs, txOut, feeAmount, inputSource, &changeSource, changeAddress != "", dustLimit)
 	if err != nil {
 		return nil, err
 	}
@@ -305,6 +332,12 @@ func (coin Btc) Transaction(txParams types.TxParams, netParams chaincfg.Params)
 
 func (coin Btc) NewUnsignedTransaction(inputs []*wire.TxIn, outputs []*wire.TxOut, relayFeePerKb btcutil.Amount,
 	fetchInputs txauthor.InputSource, fetchChange *txauthor.ChangeSource, needChange bool) (*txauthor.AuthoredTx, error) {
+	return coin.newUnsignedTransaction(inputs, outputs, relayFeePerKb, fetchInputs, fetchChange, needChange, MinNondustOutput)
+}
+
+// newUnsignedTransaction drops change below dustLimit satoshis into the fee
+func (coin Btc) newUnsignedTransaction(inputs []*wire.TxIn, outputs []*wire.TxOut, relayFeePerKb btcutil.Amount,
+	fetchInputs txauthor.InputSource, fetchChange *txauthor.ChangeSource, needChange bool, dustLimit btcutil.Amount) (*txauthor.AuthoredTx, error) {
 	targetAmount := coin.SumOutputValues(outputs)
 	estimatedSize := txsizes.EstimateSerializeSize(len(inputs), outputs, true)
 	targetFee := txrules.FeeForSerializeSize(relayFeePerKb, estimatedSize)
@@ -334,7 +367,7 @@ func (coin Btc) NewUnsignedTransaction(inputs []*wire.TxIn, outputs []*wire.TxOu
 		changeIndex := -1
 		if fetchChange != nil {
 			changeAmount := inputAmount - targetAmount - maxRequiredFee
-			if changeAmount != 0 && !IsDustAmount(changeAmount) {
+			if changeAmount != 0 && changeAmount >= dustLimit {
 				changeScript, err := fetchChange.NewScript()
 				if changeScript != nil {
 					if err != nil {
@@ -375,6 +408,13 @@ func (coin Btc) DecodeTransaction(rawTx string, testnet bool) (interface{}, erro
 
 }
 
+// BtcTxRawDecodeResult is btcjson.TxRawDecodeResult with the Omni Layer payload of the transaction,
+// DecodeTx returns it for the transactions with an Omni payload
+type BtcTxRawDecodeResult struct {
+	btcjson.TxRawDecodeResult
+	Omni *OmniPayload `json:"omni,omitempty"`
+}
+
 func (coin Btc) DecodeTx(rawTx string, params chaincfg.Params) (interface{}, error) {
 	decodeString, err := hex.DecodeString(rawTx)
 	if err != nil {
@@ -397,6 +437,10 @@ func (coin Btc) DecodeTx(rawTx string, params chaincfg.Params) (interface{}, err
 		Vin:      coin.createVinList(&mtx),
 		Vout:     coin.createVoutList(&mtx, &params, nil),
 	}
+	// only transactions carrying an Omni payload are decoded into a BtcTxRawDecodeResult
+	if omni := decodeOmniPayload(&mtx, txReply.Vout, &params); omni != nil {
+		return BtcTxRawDecodeResult{TxRawDecodeResult: txReply, Omni: omni}, nil
+	}
 	return txReply, nil
 }
 
@@ -489,6 +533,14 @@ func (coin Btc) SignTx(baseTransaction *types.BaseTransaction, testNet bool, pri
 	return coin.Sign(baseTransaction, privateKey, netParams)
 }
 
+func (coin Btc) SignTxOnNetwork(baseTransaction *types.BaseTransaction, network Network, privateKey types.PrivateKey) (*string, error) {
+	netParams, err := networkChainParams(network)
+	if err != nil {
+		return nil, err
+	}
+	return coin.Sign(baseTransaction, privateKey, netParams)
+}
+
 func (coin Btc) Sign(tx *types.BaseTransaction, derivedKey types.PrivateKey, netParams chaincfg.Params) (*string, error) {
 	authoredTx := tx.CoinTransaction.(*txauthor.AuthoredTx)
 	privateKey, err := crypto.ToECDSA(derivedKey)
@@ -624,6 +676,14 @@ func (coin Btc) GetNetParams(testNet bool) chaincfg.Params {
 	return params
 }
 
+// networkChainParams returns the chain params of a bitcoin-like network
+func networkChainParams(network Network) (chaincfg.Params, error) {
+	if network.ChainParams == nil {
+		return chaincfg.Params{}, errors.ErrorNetworkNotSupported
+	}
+	return *network.ChainParams, nil
+}
+
 func (coin Btc) GetAddressType(str string, testNet bool) (int8, error) {
 	params := coin.GetNetParams(testNet)
 	decAddr, err := btcutil.DecodeAddress(str, &params)
@@ -0,0 +1,271 @@
+package coins
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"wallet-sdk/src/errors"
+	types2 "wallet-sdk/src/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ContractCall calls Method of the contract at ToAddress, Amount is the value sent with the call.
+// Args are JSON values in the order of the method inputs: integers as numbers or decimal/0x strings,
+// addresses, bytes and fixed bytes as 0x hex strings, arrays as JSON arrays and tuples as JSON
+// objects keyed by component name or as arrays.
+type ContractCall struct {
+	EthTxParams
+	Abi    string            `json:"contractAbi"`
+	Method string            `json:"contractMethod"`
+	Args   []json.RawMessage `json:"contractArgs"`
+}
+
+// EncodeContractCall encodes the calldata of method, which is either a method name or a signature like transfer(address,uint256).
+// Address arguments are checked for the chain chainId, nil checks EIP-55 checksums.
+func EncodeContractCall(abiJson string, method string, args []json.RawMessage, chainId *big.Int) ([]byte, error) {
+	parsed, err := abi.JSON(strings.NewReader(abiJson))
+	if err != nil {
+		return nil, err
+	}
+	abiMethod, err := findAbiMethod(parsed, method)
+	if err != nil {
+		return nil, err
+	}
+	values, err := abiArguments(abiMethod, args, chainId)
+	if err != nil {
+		return nil, err
+	}
+	packed, err := abiMethod.Inputs.Pack(values...)
+	if err != nil {
+		return nil, err
+	}
+	return append(abiMethod.ID, packed...), nil
+}
+
+func createContractCallTransaction(params ContractCall, chainId *big.Int) (*types2.BaseTransaction, error) {
+	txParams := params.EthTxParams
+	err := ValidateAddressOnChain(txParams.ToAddress, chainId)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := abi.JSON(strings.NewReader(params.Abi))
+	if err != nil {
+		return nil, err
+	}
+	abiMethod, err := findAbiMethod(parsed, params.Method)
+	if err != nil {
+		return nil, err
+	}
+	value := ToWei(txParams.Amount, 18)
+	if value.Sign() != 0 && !abiMethod.IsPayable() {
+		return nil, fmt.Errorf("%w: %s is not payable", errors.ErrorInvalidAmount, abiMethod.Sig)
+	}
+	data, err := EncodeContractCall(params.Abi, abiMethod.Sig, params.Args, chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := newEvmTransaction(txParams, common.HexToAddress(txParams.ToAddress), value, data, chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := types2.BaseTransaction{}
+	transaction.CoinTransaction = tx
+	return &transaction, nil
+}
+
+func findAbiMethod(parsed abi.ABI, method string) (abi.Method, error) {
+	if abiMethod, ok := parsed.Methods[method]; ok {
+		return abiMethod, nil
+	}
+	// overloaded methods are named foo, foo0, ... by go-ethereum, the signature picks one of them
+	for _, abiMethod := range parsed.Methods {
+		if abiMethod.Sig == strings.ReplaceAll(method, " ", "") {
+			return abiMethod, nil
+		}
+	}
+	return abi.Method{}, fmt.Errorf("%w: method %s not found in abi", errors.ErrorInvalidInput, method)
+}
+
+func abiArguments(method abi.Method, args []json.RawMessage, chainId *big.Int) ([]interface{}, error) {
+	if len(args) != len(method.Inputs) {
+		return nil, fmt.Errorf("%w: %s takes %d arguments, got %d", errors.ErrorInvalidInput, method.Sig, len(method.Inputs), len(args))
+	}
+	values := make([]interface{}, len(args))
+	for i, input := range method.Inputs {
+		value, err := abiValue(input.Type, args[i], chainId)
+		if err != nil {
+			return nil, fmt.Errorf("%w: argument %d (%s %s): %v", errors.ErrorInvalidInput, i, input.Type, input.Name, err)
+		}
+		values[i] = value.Interface()
+	}
+	return values, nil
+}
+
+// abiValue converts a JSON value to the go type go-ethereum packs for t, addresses are checked for the chain chainId
+func abiValue(t abi.Type, raw json.RawMessage, chainId *big.Int) (reflect.Value, error) {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		n, err := abiInteger(raw)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return abiIntegerValue(t, n)
+	case abi.BoolTy:
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(b), nil
+	case abi.StringTy:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(s), nil
+	case abi.AddressTy:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return reflect.Value{}, err
+		}
+		if err := ValidateAddressOnChain(s, chainId); err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(common.HexToAddress(s)), nil
+	case abi.BytesTy:
+		b, err := abiBytes(raw)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(b), nil
+	case abi.FixedBytesTy, abi.FunctionTy:
+		b, err := abiBytes(raw)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value := reflect.New(t.GetType()).Elem()
+		if len(b) != value.Len() {
+			return reflect.Value{}, fmt.Errorf("expected %d bytes, got %d", value.Len(), len(b))
+		}
+		reflect.Copy(value, reflect.ValueOf(b))
+		return value, nil
+	case abi.SliceTy, abi.ArrayTy:
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			return reflect.Value{}, err
+		}
+		var value reflect.Value
+		if t.T == abi.SliceTy {
+			value = reflect.MakeSlice(t.GetType(), len(elems), len(elems))
+		} else {
+			if len(elems) != t.Size {
+				return reflect.Value{}, fmt.Errorf("expected %d elements, got %d", t.Size, len(elems))
+			}
+			value = reflect.New(t.GetType()).Elem()
+		}
+		for i, elem := range elems {
+			elemValue, err := abiValue(*t.Elem, elem, chainId)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %v", i, err)
+			}
+			value.Index(i).Set(elemValue)
+		}
+		return value, nil
+	case abi.TupleTy:
+		elems, err := abiTupleElements(t, raw)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		value := reflect.New(t.GetType()).Elem()
+		for i, elemType := range t.TupleElems {
+			elemValue, err := abiValue(*elemType, elems[i], chainId)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("component %s: %v", t.TupleRawNames[i], err)
+			}
+			value.Field(i).Set(elemValue)
+		}
+		return value, nil
+	}
+	return reflect.Value{}, fmt.Errorf("unsupported type %s", t)
+}
+
+// abiTupleElements accepts a tuple as an object keyed by component name or as an array
+func abiTupleElements(t abi.Type, raw json.RawMessage) ([]json.RawMessage, error) {
+	var elems []json.RawMessage
+	if err := json.Unmarshal(raw, &elems); err == nil {
+		if len(elems) != len(t.TupleElems) {
+			return nil, fmt.Errorf("expected %d components, got %d", len(t.TupleElems), len(elems))
+		}
+		return elems, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	if len(fields) != len(t.TupleElems) {
+		return nil, fmt.Errorf("expected %d components, got %d", len(t.TupleElems), len(fields))
+	}
+	elems = make([]json.RawMessage, len(t.TupleElems))
+	for i, name := range t.TupleRawNames {
+		field, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("missing component %s", name)
+		}
+		elems[i] = field
+	}
+	return elems, nil
+}
+
+func abiInteger(raw json.RawMessage) (*big.Int, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		var number json.Number
+		if err := json.Unmarshal(raw, &number); err != nil {
+			return nil, err
+		}
+		s = number.String()
+	}
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, fmt.Errorf("not an integer: %s", s)
+	}
+	return n, nil
+}
+
+func abiIntegerValue(t abi.Type, n *big.Int) (reflect.Value, error) {
+	if t.T == abi.UintTy {
+		if n.Sign() < 0 || n.BitLen() > t.Size {
+			return reflect.Value{}, fmt.Errorf("%s out of range for %s", n, t)
+		}
+	} else {
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+		if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return reflect.Value{}, fmt.Errorf("%s out of range for %s", n, t)
+		}
+	}
+	typ := t.GetType()
+	switch typ.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.ValueOf(n.Uint64()).Convert(typ), nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(n.Int64()).Convert(typ), nil
+	}
+	return reflect.ValueOf(n), nil
+}
+
+func abiBytes(raw json.RawMessage) ([]byte, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	if s == "" || s == "0x" {
+		return []byte{}, nil
+	}
+	return hexutil.Decode(s)
+}
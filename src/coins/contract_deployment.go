@@ -0,0 +1,108 @@
+package coins
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"wallet-sdk/src/errors"
+	types2 "wallet-sdk/src/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ContractDeployment creates a contract from Bytecode, ToAddress stays empty and Amount is the value
+// sent to the constructor. Args are the constructor arguments in the JSON format of ContractCall,
+// Abi is only needed when the constructor takes arguments.
+type ContractDeployment struct {
+	EthTxParams
+	Bytecode string            `json:"contractBytecode"`
+	Abi      string            `json:"contractAbi"`
+	Args     []json.RawMessage `json:"contractArgs"`
+}
+
+// EncodeDeployment returns the init code of a deployment, the bytecode followed by the encoded constructor arguments.
+// Address arguments are checked for the chain chainId, nil checks EIP-55 checksums.
+func EncodeDeployment(bytecode string, abiJson string, args []json.RawMessage, chainId *big.Int) ([]byte, error) {
+	code, err := hexutil.Decode(bytecode)
+	if err != nil || len(code) == 0 {
+		return nil, fmt.Errorf("%w: invalid contract bytecode", errors.ErrorInvalidInput)
+	}
+	if abiJson == "" {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("%w: constructor arguments need the abi", errors.ErrorInvalidInput)
+		}
+		return code, nil
+	}
+	parsed, err := abi.JSON(strings.NewReader(abiJson))
+	if err != nil {
+		return nil, err
+	}
+	values, err := abiArguments(parsed.Constructor, args, chainId)
+	if err != nil {
+		return nil, err
+	}
+	packed, err := parsed.Constructor.Inputs.Pack(values...)
+	if err != nil {
+		return nil, err
+	}
+	return append(code, packed...), nil
+}
+
+// CreateAddress predicts the address of a contract created by sender with a transaction of nonce
+func CreateAddress(sender string, nonce uint64) (string, error) {
+	if err := ValidateAddress(sender); err != nil {
+		return "", err
+	}
+	return crypto.CreateAddress(common.HexToAddress(sender), nonce).Hex(), nil
+}
+
+// Create2Address predicts the address of a contract created by factory with CREATE2, salt is a bytes32 hex string
+func Create2Address(factory string, salt string, initCode []byte) (string, error) {
+	return Create2AddressFromHash(factory, salt, crypto.Keccak256(initCode))
+}
+
+// Create2AddressFromHash is Create2Address from the keccak256 hash of the init code
+func Create2AddressFromHash(factory string, salt string, initCodeHash []byte) (string, error) {
+	if err := ValidateAddress(factory); err != nil {
+		return "", err
+	}
+	saltBytes, err := hexutil.Decode(salt)
+	if err != nil || len(saltBytes) != common.HashLength || len(initCodeHash) != common.HashLength {
+		return "", errors.ErrorInvalidInput
+	}
+	return crypto.CreateAddress2(common.HexToAddress(factory), common.BytesToHash(saltBytes), initCodeHash).Hex(), nil
+}
+
+func createDeploymentTransaction(params ContractDeployment, chainId *big.Int) (*types2.BaseTransaction, error) {
+	txParams := params.EthTxParams
+	if txParams.ToAddress != "" {
+		return nil, fmt.Errorf("%w: a deployment has no recipient", errors.ErrorInvalidAddress)
+	}
+	value := ToWei(txParams.Amount, 18)
+	if value.Sign() != 0 && params.Abi != "" {
+		parsed, err := abi.JSON(strings.NewReader(params.Abi))
+		if err != nil {
+			return nil, err
+		}
+		if !parsed.Constructor.IsPayable() {
+			return nil, fmt.Errorf("%w: the constructor is not payable", errors.ErrorInvalidAmount)
+		}
+	}
+	data, err := EncodeDeployment(params.Bytecode, params.Abi, params.Args, chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := newEvmTx(txParams, nil, value, data, chainId)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := types2.BaseTransaction{}
+	transaction.CoinTransaction = tx
+	return &transaction, nil
+}
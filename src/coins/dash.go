@@ -16,6 +16,10 @@ var coinDash Dash
 func init() {
 	coinDash = Dash{}
 	RegisterUtxoCoin(coinDash)
+
+	mainNetParams, testNetParams := DashMainNetParams(), DashTestNet3Params()
+	RegisterNetwork(CurrencyDash, Network{ID: "mainnet", Kind: NetworkMainNet, ChainParams: &mainNetParams})
+	RegisterNetwork(CurrencyDash, Network{ID: "testnet", Kind: NetworkTestNet, ChainParams: &testNetParams})
 }
 
 type Dash struct {
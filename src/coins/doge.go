@@ -16,6 +16,10 @@ var coinDoge Doge
 func init() {
 	coinDoge = Doge{}
 	RegisterUtxoCoin(coinDoge)
+
+	mainNetParams, testNetParams := DogeMainNetParams(), DogeTestNet3Params()
+	RegisterNetwork(CurrencyDoge, Network{ID: "mainnet", Kind: NetworkMainNet, ChainParams: &mainNetParams})
+	RegisterNetwork(CurrencyDoge, Network{ID: "testnet", Kind: NetworkTestNet, ChainParams: &testNetParams})
 }
 
 type Doge struct {
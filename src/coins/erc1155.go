@@ -12,6 +12,10 @@ type Erc1155TxParams struct {
 	ContractAddress string             `json:"commonContractAddress"`
 	FromAddress     string             `json:"fromAddress"`
 	BatchData       []Erc1155BatchData `json:"erc1155BatchData"`
+	// Method is the call made, ToAddress is the recipient or the operator
+	Method NftMethod `json:"nftMethod"`
+	// TransferData is the hex data a safeTransferFrom passes to onERC1155Received
+	TransferData string `json:"nftTransferData"`
 }
 
 type Erc1155BatchData struct {
@@ -36,7 +40,7 @@ func (coin Erc1155) GetCurrency() string {
 }
 
 func (coin Erc1155) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
-	return createErc1155TokenTransaction(params)
+	return createErc1155TokenTransaction(params, NetworkFromTestNet(coin.GetCurrency(), testNet).ChainID)
 }
 
 func (coin Erc1155) GetTransactionParamsFromJson(paramsJson string) types.TxParams {
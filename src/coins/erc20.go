@@ -29,7 +29,7 @@ func (coin Erc20) GetCurrency() string {
 }
 
 func (coin Erc20) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
-	return createTokenTransaction(params)
+	return createTokenTransaction(params, NetworkFromTestNet(coin.GetCurrency(), testNet).ChainID)
 }
 
 func (coin Erc20) GetTransactionParamsFromJson(paramsJson string) types.TxParams {
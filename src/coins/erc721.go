@@ -16,6 +16,10 @@ type Erc721TxParams struct {
 	ContractAddress string       `json:"commonContractAddress"`
 	FromAddress     string       `json:"fromAddress"`
 	TokenId         types.BigInt `json:"ethereumTokenId"`
+	// Method is the call made, ToAddress is the recipient, the approved address or the operator
+	Method NftMethod `json:"nftMethod"`
+	// TransferData is the hex data a safeTransferFrom passes to onERC721Received
+	TransferData string `json:"nftTransferData"`
 }
 
 var coinErc721 Erc721
@@ -31,7 +35,7 @@ func (coin Erc721) GetCurrency() string {
 }
 
 func (coin Erc721) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
-	return createErc721TokenTransaction(params)
+	return createErc721TokenTransaction(params, NetworkFromTestNet(coin.GetCurrency(), testNet).ChainID)
 }
 
 func (coin Erc721) GetTransactionParamsFromJson(paramsJson string) types.TxParams {
@@ -17,6 +17,7 @@ var coinEtc Etc
 func init() {
 	coinEtc = Etc{}
 	RegisterEthLikeCoin(coinEtc)
+	registerEvmNetworks(CurrencyEtc, ETC_MAIN, ETC_TEST)
 }
 
 type Etc struct {
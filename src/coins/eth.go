@@ -11,11 +11,12 @@ import (
 	"math/big"
 	"strings"
 	"wallet-sdk/src/deriver"
+	"wallet-sdk/src/errors"
 	"wallet-sdk/src/types"
 )
 
 const (
-	TESTNET     = 5 //Goerli
+	TESTNET     = 11155111 //Sepolia
 	MAINNET     = 1
 	CurrencyEth = "ETH"
 )
@@ -28,6 +29,17 @@ type EthTxParams struct {
 	GasPrice  types.BigInt    `json:"ethereumGasPrice"`
 	GasLimit  types.BigInt    `json:"ethereumGasLimit"`
 	Data      string          `json:"ethereumData"`
+	// MaxFeePerGas and MaxPriorityFeePerGas build an EIP-1559 transaction instead of a legacy one when set
+	MaxFeePerGas         types.BigInt `json:"ethereumMaxFeePerGas"`
+	MaxPriorityFeePerGas types.BigInt `json:"ethereumMaxPriorityFeePerGas"`
+	// AccessList builds an EIP-2930 transaction, or is added to the EIP-1559 one
+	AccessList []EthAccessTuple `json:"ethereumAccessList"`
+}
+
+// EthAccessTuple is an access list entry, an address and the storage slots the transaction reads or writes
+type EthAccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
 }
 
 var coinEth Eth
@@ -36,6 +48,10 @@ func init() {
 	coinEth = Eth{}
 	RegisterEthLikeCoin(coinEth)
 
+	RegisterNetwork(CurrencyEth, Network{ID: "mainnet", Kind: NetworkMainNet, ChainID: big.NewInt(MAINNET)})
+	RegisterNetwork(CurrencyEth, Network{ID: "sepolia", Kind: NetworkTestNet, ChainID: big.NewInt(TESTNET)})
+	RegisterNetwork(CurrencyEth, Network{ID: "holesky", Kind: NetworkTestNet, ChainID: big.NewInt(17000)})
+	RegisterNetwork(CurrencyEth, Network{ID: "devnet", Kind: NetworkDevNet, ChainID: big.NewInt(1337)})
 }
 
 type Eth struct {
@@ -87,22 +103,30 @@ func (coin Eth) GenerateAddress(keyByte types.PrivateKey, testNet bool) (*types.
 
 }
 
+func (coin Eth) GenerateChecksumAddress(keyByte types.PrivateKey, testNet bool) (*types.CoinAddress, error) {
+	privateKey, err := crypto.ToECDSA(keyByte)
+	if err != nil {
+		return nil, err
+	}
+	return createChecksumAddress(privateKey, nil)
+}
+
 func (coin Eth) GetEmptyTransactionParams() types.TxParams {
 	return EthTxParams{}
 
 }
 
 func (coin Eth) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
-	return createTransaction(params)
+	return createTransaction(params, NetworkFromTestNet(coin.GetCurrency(), testNet).ChainID)
 
 }
 
 func (coin Eth) DecodeTransaction(rawTx string, testnet bool) (interface{}, error) {
-	return DecodeTx(rawTx)
+	return DecodeEvmTx(rawTx, coin.GetDecimal())
 }
 
 func (coin Eth) CreateDappTransaction(params types.TxParams) (*types.BaseTransaction, error) {
-	return createTransaction(params)
+	return createTransaction(params, NetworkFromTestNet(coin.GetCurrency(), false).ChainID)
 }
 
 func (coin Eth) SignTx(baseTransaction *types.BaseTransaction, testNet bool, privateKey types.PrivateKey) (*string, error) {
@@ -116,6 +140,14 @@ func (coin Eth) SignTx(baseTransaction *types.BaseTransaction, testNet bool, pri
 
 }
 
+// SignTxOnNetwork signs with the chain id of network, shared by the tokens of the chain
+func (coin Eth) SignTxOnNetwork(baseTransaction *types.BaseTransaction, network Network, privateKey types.PrivateKey) (*string, error) {
+	if network.ChainID == nil {
+		return nil, errors.ErrorNetworkNotSupported
+	}
+	return signTx(network.ChainID, baseTransaction, privateKey)
+}
+
 func (coin Eth) SignMessage(message string, privateKey types.PrivateKey) (string, error) {
 
 	var signFormat = "\x19Ethereum Signed Message:\n%d%s"
@@ -135,11 +167,20 @@ func (coin Eth) SignMessage(message string, privateKey types.PrivateKey) (string
 	return hexutil.Encode(signatureByte), nil
 }
 
+// GetTransactionParamsFromJson returns a ContractCall when the JSON names a contract method,
+// a ContractDeployment when it has contract bytecode, EthTxParams otherwise
 func (coin Eth) GetTransactionParamsFromJson(paramsJson string) types.TxParams {
-	params := EthTxParams{}
+	params := ContractCall{}
 	err := json.Unmarshal([]byte(paramsJson), &params)
 	if err != nil {
 		return nil
 	}
-	return params
+	if params.Method != "" {
+		return params
+	}
+	deployment := ContractDeployment{}
+	if err = json.Unmarshal([]byte(paramsJson), &deployment); err == nil && deployment.Bytecode != "" {
+		return deployment
+	}
+	return params.EthTxParams
 }
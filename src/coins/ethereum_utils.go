@@ -3,51 +3,47 @@ package coins
 import (
 	"crypto/ecdsa"
 	"encoding/hex"
-	"errors"
 	"fmt"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/shopspring/decimal"
 	"golang.org/x/crypto/sha3"
-	"log"
 	"math/big"
 	"strings"
 	errors2 "wallet-sdk/src/errors"
 	types2 "wallet-sdk/src/types"
 )
 
-func createTransaction(params types2.TxParams) (*types2.BaseTransaction, error) {
+// createTransaction builds a transfer, contract call or deployment, addresses are checked for the chain chainId
+func createTransaction(params types2.TxParams, chainId *big.Int) (*types2.BaseTransaction, error) {
+	if contractCall, ok := params.(ContractCall); ok {
+		return createContractCallTransaction(contractCall, chainId)
+	}
+	if deployment, ok := params.(ContractDeployment); ok {
+		return createDeploymentTransaction(deployment, chainId)
+	}
 	txParams := params.(EthTxParams)
 
-	err := validateAddr(txParams.ToAddress)
+	err := ValidateAddressOnChain(txParams.ToAddress, chainId)
 	if err != nil {
 		return nil, err
 	}
 	wei := ToWei(txParams.Amount, 18)
-	var nonce = txParams.Nonce
-	var gasprice = txParams.GasPrice.Int
-	var gaslimit = txParams.GasLimit.Int
-	address := common.HexToAddress(txParams.ToAddress)
-	legacyTx := types.LegacyTx{
-		GasPrice: &gasprice,
-		Nonce:    uint64(nonce),
-		Gas:      gaslimit.Uint64(),
-		Value:    wei,
-		To:       &address,
-	}
+	var data []byte
 	if txParams.Data != "" {
-		decode, err := hexutil.Decode(txParams.Data)
+		data, err = hexutil.Decode(txParams.Data)
 		if err != nil {
 			return nil, err
 		}
-		legacyTx.Data = decode
 	}
 
-	tx := types.NewTx(&legacyTx)
+	tx, err := newEvmTransaction(txParams, common.HexToAddress(txParams.ToAddress), wei, data, chainId)
+	if err != nil {
+		return nil, err
+	}
 
 	transaction := types2.BaseTransaction{}
 	transaction.CoinTransaction = tx
@@ -55,10 +51,107 @@ func createTransaction(params types2.TxParams) (*types2.BaseTransaction, error)
 
 }
 
-func validateAddr(address string) error {
-	toAddrBytes, err := hex.DecodeString(strings.TrimPrefix(address, "0x"))
-	if err != nil || len(toAddrBytes) != common.AddressLength {
-		return errors2.ErrorInvalidAddress
+// newEvmTransaction builds a DynamicFeeTx when maxFeePerGas is set, an AccessListTx when only an access list
+// is given, otherwise a LegacyTx paying gasPrice. The chain id of typed transactions is filled in by the signer.
+func newEvmTransaction(txParams EthTxParams, to common.Address, value *big.Int, data []byte, chainId *big.Int) (*types.Transaction, error) {
+	return newEvmTx(txParams, &to, value, data, chainId)
+}
+
+// newEvmTx is newEvmTransaction creating a contract when to is nil
+func newEvmTx(txParams EthTxParams, to *common.Address, value *big.Int, data []byte, chainId *big.Int) (*types.Transaction, error) {
+	accessList, err := toAccessList(txParams.AccessList, chainId)
+	if err != nil {
+		return nil, err
+	}
+	var gaslimit = txParams.GasLimit.Int
+	if txParams.MaxFeePerGas.Sign() == 0 {
+		var gasprice = txParams.GasPrice.Int
+		if len(accessList) > 0 {
+			return types.NewTx(&types.AccessListTx{
+				Nonce:      uint64(txParams.Nonce),
+				GasPrice:   &gasprice,
+				Gas:        gaslimit.Uint64(),
+				To:         to,
+				Value:      value,
+				Data:       data,
+				AccessList: accessList,
+			}), nil
+		}
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    uint64(txParams.Nonce),
+			GasPrice: &gasprice,
+			Gas:      gaslimit.Uint64(),
+			To:       to,
+			Value:    value,
+			Data:     data,
+		}), nil
+	}
+
+	var maxFee = txParams.MaxFeePerGas.Int
+	var maxPriorityFee = txParams.MaxPriorityFeePerGas.Int
+	if maxPriorityFee.Cmp(&maxFee) > 0 {
+		return nil, errors2.ErrorInvalidFee
+	}
+	return types.NewTx(&types.DynamicFeeTx{
+		Nonce:      uint64(txParams.Nonce),
+		GasTipCap:  &maxPriorityFee,
+		GasFeeCap:  &maxFee,
+		Gas:        gaslimit.Uint64(),
+		To:         to,
+		Value:      value,
+		Data:       data,
+		AccessList: accessList,
+	}), nil
+}
+
+func toAccessList(tuples []EthAccessTuple, chainId *big.Int) (types.AccessList, error) {
+	var accessList types.AccessList
+	for _, tuple := range tuples {
+		if err := ValidateAddressOnChain(tuple.Address, chainId); err != nil {
+			return nil, err
+		}
+		storageKeys := make([]common.Hash, 0, len(tuple.StorageKeys))
+		for _, key := range tuple.StorageKeys {
+			keyBytes, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+			if err != nil || len(keyBytes) != common.HashLength {
+				return nil, errors2.ErrorInvalidInput
+			}
+			storageKeys = append(storageKeys, common.BytesToHash(keyBytes))
+		}
+		accessList = append(accessList, types.AccessTuple{Address: common.HexToAddress(tuple.Address), StorageKeys: storageKeys})
+	}
+	return accessList, nil
+}
+
+// IntrinsicGas returns the gas a transaction is charged before executing: the base cost, the calldata,
+// the access list and, for contract creations, the init code words (Shanghai rules)
+func IntrinsicGas(data []byte, accessList types.AccessList, isContractCreation bool) uint64 {
+	gas := params.TxGas
+	if isContractCreation {
+		gas = params.TxGasContractCreation
+		gas += (uint64(len(data)) + 31) / 32 * params.InitCodeWordGas
+	}
+	for _, b := range data {
+		if b == 0 {
+			gas += params.TxDataZeroGas
+		} else {
+			gas += params.TxDataNonZeroGasEIP2028
+		}
+	}
+	gas += uint64(len(accessList)) * params.TxAccessListAddressGas
+	gas += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+	return gas
+}
+
+// CheckIntrinsicGas returns an error when the gas limit of an evm transaction can't cover its intrinsic gas
+func CheckIntrinsicGas(baseTransaction *types2.BaseTransaction) error {
+	tx, ok := baseTransaction.CoinTransaction.(*types.Transaction)
+	if !ok {
+		return errors2.ErrorInvalidInput
+	}
+	intrinsicGas := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil)
+	if tx.Gas() < intrinsicGas {
+		return fmt.Errorf("%w: gas limit %d, intrinsic gas %d", errors2.ErrorGasLimitTooLow, tx.Gas(), intrinsicGas)
 	}
 	return nil
 }
@@ -70,6 +163,17 @@ func createAddress(privateKey *ecdsa.PrivateKey, testNet bool) (*types2.CoinAddr
 	return &adddress, nil
 }
 
+// createChecksumAddress is createAddress with the EIP-55 checksum, or the EIP-1191 one of chainId when not nil
+func createChecksumAddress(privateKey *ecdsa.PrivateKey, chainId *big.Int) (*types2.CoinAddress, error) {
+	checksum, err := ToChecksumAddress(crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), chainId)
+	if err != nil {
+		return nil, err
+	}
+	var adddress = types2.CoinAddress{}
+	adddress.AddressStr = checksum
+	return &adddress, nil
+}
+
 func signTx(chainId *big.Int, baseTransaction *types2.BaseTransaction, privateKey types2.PrivateKey) (*string, error) {
 	toECDSA, err := crypto.ToECDSA(privateKey)
 	if err != nil {
@@ -77,20 +181,25 @@ func signTx(chainId *big.Int, baseTransaction *types2.BaseTransaction, privateKe
 	}
 
 	tx := baseTransaction.CoinTransaction.(*types.Transaction)
+	err = CheckIntrinsicGas(baseTransaction)
 	if err != nil {
 		return nil, err
 	}
-	signer := types.NewEIP155Signer(chainId)
+	// replacements keep the chain id of the pending transaction, the one of a new transaction is 0
+	if txChainId := tx.ChainId(); tx.Protected() && txChainId.Sign() != 0 && txChainId.Cmp(chainId) != 0 {
+		return nil, fmt.Errorf("%w: transaction of chain %s signed for chain %s", errors2.ErrorChainIdMismatch, txChainId, chainId)
+	}
+	signer := types.NewLondonSigner(chainId)
 	signTx, err := types.SignTx(tx, signer, toECDSA)
 
 	if err != nil {
-		log.Fatal(err)
-		return nil, nil
+		return nil, err
 	}
-	bytes, err := rlp.EncodeToBytes(signTx)
+	// typed transactions are broadcast as type || payload, legacy ones as plain rlp
+	bytes, err := signTx.MarshalBinary()
 
 	if err != nil {
-		return nil, nil
+		return nil, err
 	}
 	rawTxHex := common.Bytes2Hex(bytes)
 	var txHex = "0x" + rawTxHex
@@ -101,11 +210,10 @@ func DecodeTx(rawTx string) (*types.Transaction, error) {
 	trimedTx := strings.TrimPrefix(rawTx, "0x")
 	raw, err := hex.DecodeString(trimedTx)
 	if err != nil {
-		log.Fatal(err)
 		return nil, err
 	}
-	var tx *types.Transaction
-	err = rlp.DecodeBytes(raw, &tx)
+	tx := new(types.Transaction)
+	err = tx.UnmarshalBinary(raw)
 	if err != nil {
 		return nil, err
 	}
@@ -137,17 +245,19 @@ func ToWei(iamount interface{}, decimals int64) *big.Int {
 	return wei
 }
 
-func createTokenTransaction(params types2.TxParams) (*types2.BaseTransaction, error) {
+// createTokenTransaction builds an erc20 transfer, the token is checked against the registry of the chain chainId
+func createTokenTransaction(params types2.TxParams, chainId *big.Int) (*types2.BaseTransaction, error) {
 	extraParams := params.(Erc20TxParams)
-	err := validateAddr(extraParams.ToAddress)
+	err := ValidateAddressOnChain(extraParams.ToAddress, chainId)
 	if err != nil {
 		return nil, err
 	}
-	var nonce = extraParams.Nonce
-	var gasprice = extraParams.GasPrice.Int
-
-	var gaslimit = extraParams.GasLimit.Int
-
+	if err = ValidateAddressOnChain(extraParams.ContractAddress, chainId); err != nil {
+		return nil, errors2.ErrorInvalidContractAddress
+	}
+	if err = checkRegisteredToken(chainId, extraParams.ContractAddress, extraParams.TokenDecimal); err != nil {
+		return nil, err
+	}
 	var contractAddress = extraParams.ContractAddress
 	var tokenDecimal = extraParams.TokenDecimal
 
@@ -165,152 +275,52 @@ func createTokenTransaction(params types2.TxParams) (*types2.BaseTransaction, er
 	data = append(data, methodID...)
 	data = append(data, paddedAddress...)
 	data = append(data, paddedAmount...)
-	tx := types.NewTransaction(
-		big.NewInt(nonce).Uint64(),
-		common.HexToAddress(contractAddress),
-		big.NewInt(0), gaslimit.Uint64(), &gasprice,
-		data,
-	)
-
-	transaction := types2.BaseTransaction{}
-	transaction.CoinTransaction = tx
-	return &transaction, nil
-
-}
-
-func createErc721TokenTransaction(params types2.TxParams) (*types2.BaseTransaction, error) {
-	extraParams := params.(Erc721TxParams)
-
-	err := validateAddr(extraParams.ToAddress)
+	tx, err := newEvmTransaction(extraParams.EthTxParams, common.HexToAddress(contractAddress), big.NewInt(0), data, chainId)
 	if err != nil {
 		return nil, err
 	}
-	var nonce = extraParams.Nonce
-	var gasprice = extraParams.GasPrice.Int
-
-	var gaslimit = extraParams.GasLimit.Int
-
-	var contractAddress = extraParams.ContractAddress
-	fmt.Println("contractAddress:", contractAddress)
 
-	transferFnSignature := []byte("safeTransferFrom(address,address,uint256)")
-	hash := sha3.NewLegacyKeccak256()
-	hash.Write(transferFnSignature)
-	methodID := hash.Sum(nil)[:4]
-
-	paddedAddress := common.LeftPadBytes(common.HexToAddress(extraParams.ToAddress).Bytes(), 32)
-
-	paddedFromAddress := common.LeftPadBytes(common.HexToAddress(extraParams.FromAddress).Bytes(), 32)
-
-	paddedTokenId := common.LeftPadBytes(extraParams.TokenId.Bytes(), 32)
-
-	var data []byte
-	data = append(data, methodID...)
-	data = append(data, paddedFromAddress...)
-	data = append(data, paddedAddress...)
-	data = append(data, paddedTokenId...)
-
-	fmt.Println("contractAddress-to:", common.HexToAddress(contractAddress).String())
-	tx := types.NewTransaction(
-		big.NewInt(nonce).Uint64(),
-		common.HexToAddress(contractAddress),
-		big.NewInt(0), gaslimit.Uint64(), &gasprice,
-		data,
-	)
-	fmt.Println("contractAddress-to:", tx.To().String())
 	transaction := types2.BaseTransaction{}
 	transaction.CoinTransaction = tx
 	return &transaction, nil
 
 }
 
-func createErc1155TokenTransaction(params types2.TxParams) (*types2.BaseTransaction, error) {
-	extraParams := params.(Erc1155TxParams)
-	err := validateAddr(extraParams.ToAddress)
-	if err != nil {
-		return nil, err
-	}
-	if len(extraParams.BatchData) == 0 {
-		return nil, errors.New("no token data")
-	}
-
-	if len(extraParams.BatchData) > 1 {
-		return createErc1155BatchTransaction(params)
+func createErc721TokenTransaction(params types2.TxParams, chainId *big.Int) (*types2.BaseTransaction, error) {
+	extraParams := params.(Erc721TxParams)
+	if err := ValidateAddressOnChain(extraParams.ContractAddress, chainId); err != nil {
+		return nil, errors2.ErrorInvalidContractAddress
 	}
-
-	tokenData := extraParams.BatchData[0]
-
-	var nonce = extraParams.Nonce
-	var gasprice = extraParams.GasPrice.Int
-
-	var gaslimit = extraParams.GasLimit.Int
-
-	var contractAddress = extraParams.ContractAddress
-
-	var method = `[	{ "type" : "function", "name" : "safeTransferFrom", "inputs" : [  { "name" : "from", "type" : "address" },{ "name" : "_to", "type" : "address" },{ "name" : "_tokenId", "type" : "uint256" },{ "name" : "amount", "type" : "uint256" },{ "name" : "data", "type" : "bytes" } ] }]`
-	json, err := abi.JSON(strings.NewReader(method))
+	data, err := encodeErc721Call(extraParams, chainId)
 	if err != nil {
 		return nil, err
 	}
 
-	bytes, err := json.Pack("safeTransferFrom", common.HexToAddress(extraParams.FromAddress),
-		common.HexToAddress(extraParams.ToAddress), &tokenData.TokenId.Int, &tokenData.Amount.Int, []byte(""))
+	tx, err := newEvmTransaction(extraParams.EthTxParams, common.HexToAddress(extraParams.ContractAddress), big.NewInt(0), data, chainId)
 	if err != nil {
 		return nil, err
 	}
-
-	tx := types.NewTransaction(
-		big.NewInt(nonce).Uint64(),
-		common.HexToAddress(contractAddress),
-		big.NewInt(0), gaslimit.Uint64(), &gasprice,
-		bytes,
-	)
-
 	transaction := types2.BaseTransaction{}
 	transaction.CoinTransaction = tx
 	return &transaction, nil
 
 }
 
-func createErc1155BatchTransaction(params types2.TxParams) (*types2.BaseTransaction, error) {
+func createErc1155TokenTransaction(params types2.TxParams, chainId *big.Int) (*types2.BaseTransaction, error) {
 	extraParams := params.(Erc1155TxParams)
-	err := validateAddr(extraParams.ToAddress)
-	if err != nil {
-		return nil, err
+	if err := ValidateAddressOnChain(extraParams.ContractAddress, chainId); err != nil {
+		return nil, errors2.ErrorInvalidContractAddress
 	}
-	var nonce = extraParams.Nonce
-	var gasprice = extraParams.GasPrice.Int
-
-	var gaslimit = extraParams.GasLimit.Int
-
-	var contractAddress = extraParams.ContractAddress
-
-	var method = `[	{ "type" : "function", "name" : "safeBatchTransferFrom", "inputs" : [  { "name" : "from", "type" : "address" },{ "name" : "_to", "type" : "address" },{ "name" : "_tokenId", "type" : "uint256[]" },{ "name" : "amount", "type" : "uint256[]" },{ "name" : "data", "type" : "bytes" } ] }]`
-	json, err := abi.JSON(strings.NewReader(method))
+	data, err := encodeErc1155Call(extraParams, chainId)
 	if err != nil {
 		return nil, err
 	}
-	var tokenIds []*big.Int
-	var amounts []*big.Int
-	for index := range extraParams.BatchData {
-		var data = extraParams.BatchData[index]
-		tokenIds = append(tokenIds, &data.TokenId.Int)
-		amounts = append(amounts, &data.Amount.Int)
-	}
 
-	bytes, err := json.Pack("safeBatchTransferFrom", common.HexToAddress(extraParams.FromAddress),
-		common.HexToAddress(extraParams.ToAddress), tokenIds, amounts, []byte(""))
+	tx, err := newEvmTransaction(extraParams.EthTxParams, common.HexToAddress(extraParams.ContractAddress), big.NewInt(0), data, chainId)
 	if err != nil {
 		return nil, err
 	}
 
-	tx := types.NewTransaction(
-		big.NewInt(nonce).Uint64(),
-		common.HexToAddress(contractAddress),
-		big.NewInt(0), gaslimit.Uint64(), &gasprice,
-		bytes,
-	)
-
 	transaction := types2.BaseTransaction{}
 	transaction.CoinTransaction = tx
 	return &transaction, nil
@@ -7,7 +7,7 @@ import (
 )
 
 const (
-	ETHW_TEST    = 10001
+	ETHW_TEST    = 10002 // Iceberg
 	ETHW_MAIN    = 10001
 	CurrencyEthw = "ETHW"
 )
@@ -18,6 +18,7 @@ func init() {
 	coinEthw = Ethw{}
 	RegisterEthLikeCoin(coinEthw)
 
+	registerEvmNetworks(CurrencyEthw, ETHW_MAIN, ETHW_TEST)
 }
 
 type Ethw struct {
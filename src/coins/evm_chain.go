@@ -0,0 +1,343 @@
+package coins
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"wallet-sdk/src/errors"
+	"wallet-sdk/src/types"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// token variants created for every evm chain, the currency is <Symbol>_<suffix>
+const (
+	EvmTokenErc20   = "ERC20"
+	EvmTokenErc721  = "ERC721"
+	EvmTokenErc1155 = "ERC1155"
+)
+
+// EvmNetworkConfig is one network of an evm chain, the first network of each kind
+// is the one selected by the testNet flag
+type EvmNetworkConfig struct {
+	ID      string      `json:"id"`
+	Kind    NetworkKind `json:"kind"`
+	ChainId uint64      `json:"chainId"`
+	// Uniswap are the Uniswap contracts of the network, for the swap builders
+	Uniswap *UniswapRouters `json:"uniswap"`
+}
+
+// EvmChainConfig describes an evm chain served by the Eth engine
+type EvmChainConfig struct {
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+	// CoinType is the SLIP-44 coin type used in the bip44 path, 60 when not set
+	CoinType        uint32 `json:"coinType"`
+	SupportsEIP1559 bool   `json:"supportsEIP1559"`
+	// EIP1191 checksums addresses with the chain id, as RSK does
+	EIP1191  bool               `json:"eip1191"`
+	Networks []EvmNetworkConfig `json:"networks"`
+}
+
+type EvmChain struct {
+	Eth
+	config EvmChainConfig
+}
+
+type EvmChainErc20 struct {
+	EvmChain
+}
+
+type EvmChainErc721 struct {
+	EvmChain
+}
+
+type EvmChainErc1155 struct {
+	EvmChain
+}
+
+// RegisterEvmChain registers an evm chain described by config with its ERC20, ERC721 and ERC1155 variants,
+// returning the native coin
+func RegisterEvmChain(config EvmChainConfig) (Coin, error) {
+	coin, err := NewEvmChain(config)
+	if err != nil {
+		return nil, err
+	}
+	if err = coin.checkNotRegistered(); err != nil {
+		return nil, err
+	}
+	coin.register()
+	return coin, nil
+}
+
+// RegisterEvmChainsFromJson registers every chain of a JSON array of EvmChainConfig.
+// Nothing is registered when one of the configs is invalid.
+func RegisterEvmChainsFromJson(configJson []byte) ([]Coin, error) {
+	var configs []EvmChainConfig
+	if err := json.Unmarshal(configJson, &configs); err != nil {
+		return nil, err
+	}
+	var chains []EvmChain
+	symbols := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		coin, err := NewEvmChain(config)
+		if err != nil {
+			return nil, err
+		}
+		if err = coin.checkNotRegistered(); err != nil {
+			return nil, err
+		}
+		if symbols[coin.GetCurrency()] {
+			return nil, errors.ErrorCurrencyAlreadyRegistered
+		}
+		symbols[coin.GetCurrency()] = true
+		chains = append(chains, coin)
+	}
+
+	var coins []Coin
+	for _, coin := range chains {
+		coin.register()
+		coins = append(coins, coin)
+	}
+	return coins, nil
+}
+
+// RegisterEvmChainsFromFile registers the chains of a JSON file, see RegisterEvmChainsFromJson
+func RegisterEvmChainsFromFile(path string) ([]Coin, error) {
+	configJson, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return RegisterEvmChainsFromJson(configJson)
+}
+
+// NewEvmChain validates config and fills in its defaults, without registering the coin
+func NewEvmChain(config EvmChainConfig) (EvmChain, error) {
+	config.Symbol = strings.ToUpper(config.Symbol)
+	if config.Symbol == "" || strings.Contains(config.Symbol, "_") {
+		return EvmChain{}, errors.ErrorInvalidInput
+	}
+	hasMainNet := false
+	ids := make(map[string]bool, len(config.Networks))
+	for _, network := range config.Networks {
+		if network.ID == "" || ids[network.ID] || network.ChainId == 0 {
+			return EvmChain{}, errors.ErrorInvalidInput
+		}
+		if network.Kind != NetworkMainNet && network.Kind != NetworkTestNet && network.Kind != NetworkDevNet {
+			return EvmChain{}, fmt.Errorf("unknown network kind %s", network.Kind)
+		}
+		if network.Uniswap != nil {
+			// the chain isn't registered yet, ValidateAddressOnChain wouldn't know it uses EIP-1191
+			var checksumChainId *big.Int
+			if config.EIP1191 {
+				checksumChainId = new(big.Int).SetUint64(network.ChainId)
+			}
+			if err := validateUniswapRouters(*network.Uniswap, checksumChainId); err != nil {
+				return EvmChain{}, err
+			}
+		}
+		ids[network.ID] = true
+		hasMainNet = hasMainNet || network.Kind == NetworkMainNet
+	}
+	if !hasMainNet {
+		return EvmChain{}, fmt.Errorf("%s has no mainnet", config.Symbol)
+	}
+	if config.Decimals == 0 {
+		config.Decimals = 18
+	}
+	if config.CoinType == 0 {
+		config.CoinType = 60
+	}
+	return EvmChain{config: config}, nil
+}
+
+func (coin EvmChain) checkNotRegistered() error {
+	for _, currency := range coin.currencies() {
+		if _, err := GetCoin(currency); err == nil {
+			return errors.ErrorCurrencyAlreadyRegistered
+		}
+	}
+	return nil
+}
+
+func (coin EvmChain) currencies() []string {
+	symbol := coin.config.Symbol
+	return []string{symbol, symbol + "_" + EvmTokenErc20, symbol + "_" + EvmTokenErc721, symbol + "_" + EvmTokenErc1155}
+}
+
+// register adds the coin, its token variants and its networks
+func (coin EvmChain) register() {
+	RegisterEthLikeCoin(coin)
+	RegisterEthLikeCoin(EvmChainErc20{coin})
+	erc721 := EvmChainErc721{coin}
+	RegisterEthLikeCoin(erc721)
+	RegisterNftToken(erc721)
+	erc1155 := EvmChainErc1155{coin}
+	RegisterEthLikeCoin(erc1155)
+	RegisterNftToken(erc1155)
+
+	for _, network := range coin.config.Networks {
+		chainId := new(big.Int).SetUint64(network.ChainId)
+		RegisterNetwork(coin.config.Symbol, Network{ID: network.ID, Kind: network.Kind, ChainID: chainId})
+		if coin.config.EIP1191 {
+			registerEip1191ChainId(chainId)
+		}
+		if network.Uniswap != nil {
+			uniswapRouters[chainId.String()] = *network.Uniswap
+		}
+	}
+}
+
+// registerEvmNetworks registers the mainnet and testnet of a built-in evm chain
+func registerEvmNetworks(currency string, mainChainId int64, testChainId int64) {
+	RegisterNetwork(currency, Network{ID: "mainnet", Kind: NetworkMainNet, ChainID: big.NewInt(mainChainId)})
+	RegisterNetwork(currency, Network{ID: "testnet", Kind: NetworkTestNet, ChainID: big.NewInt(testChainId)})
+}
+
+func (coin EvmChain) GetCurrency() string {
+	return coin.config.Symbol
+}
+
+func (coin EvmChain) ChainName() string {
+	return coin.config.Symbol
+}
+
+func (coin EvmChain) GetDecimal() int {
+	return coin.config.Decimals
+}
+
+func (coin EvmChain) GetPath(index int64, testNet bool) string {
+	return fmt.Sprintf(coin.GetBasePath(testNet), 0, 0, index)
+}
+
+func (coin EvmChain) GetBasePath(testNet bool) string {
+	return fmt.Sprintf("m/44'/%d'/%%d'/%%d/%%d", coin.config.CoinType)
+}
+
+// GenerateChecksumAddress returns the EIP-1191 address of the network selected by testNet on EIP-1191 chains,
+// the EIP-55 one otherwise
+func (coin EvmChain) GenerateChecksumAddress(keyByte types.PrivateKey, testNet bool) (*types.CoinAddress, error) {
+	privateKey, err := crypto.ToECDSA(keyByte)
+	if err != nil {
+		return nil, err
+	}
+	if !coin.config.EIP1191 {
+		return createChecksumAddress(privateKey, nil)
+	}
+	network := NetworkFromTestNet(coin.config.Symbol, testNet)
+	if network.ChainID == nil {
+		return nil, errors.ErrorNetworkNotSupported
+	}
+	return createChecksumAddress(privateKey, network.ChainID)
+}
+
+func (coin EvmChain) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
+	txParams, ok := params.(EthTxParams)
+	switch wrapped := params.(type) {
+	case ContractCall:
+		txParams, ok = wrapped.EthTxParams, true
+	case ContractDeployment:
+		txParams, ok = wrapped.EthTxParams, true
+	}
+	if !ok {
+		return nil, errors.ErrorInvalidInput
+	}
+	if err := coin.checkFees(txParams); err != nil {
+		return nil, err
+	}
+	return createTransaction(params, NetworkFromTestNet(coin.config.Symbol, testNet).ChainID)
+}
+
+func (coin EvmChain) CreateDappTransaction(params types.TxParams) (*types.BaseTransaction, error) {
+	return coin.CreateTransaction(params, false)
+}
+
+func (coin EvmChain) DecodeTransaction(rawTx string, testnet bool) (interface{}, error) {
+	return DecodeEvmTx(rawTx, coin.config.Decimals)
+}
+
+func (coin EvmChain) SignTx(baseTransaction *types.BaseTransaction, testNet bool, privateKey types.PrivateKey) (*string, error) {
+	return coin.SignTxOnNetwork(baseTransaction, NetworkFromTestNet(coin.config.Symbol, testNet), privateKey)
+}
+
+// checkFees rejects EIP-1559 fees on chains without a base fee
+func (coin EvmChain) checkFees(txParams EthTxParams) error {
+	if !coin.config.SupportsEIP1559 && txParams.MaxFeePerGas.Sign() != 0 {
+		return fmt.Errorf("%w: %s doesn't support EIP-1559", errors.ErrorInvalidFee, coin.config.Symbol)
+	}
+	return nil
+}
+
+func (coin EvmChainErc20) GetCurrency() string {
+	return coin.config.Symbol + "_" + EvmTokenErc20
+}
+
+func (coin EvmChainErc20) GetEmptyTransactionParams() types.TxParams {
+	return Erc20TxParams{}
+}
+
+func (coin EvmChainErc20) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
+	if err := coin.checkFees(params.(Erc20TxParams).EthTxParams); err != nil {
+		return nil, err
+	}
+	return createTokenTransaction(params, NetworkFromTestNet(coin.GetCurrency(), testNet).ChainID)
+}
+
+func (coin EvmChainErc20) GetTransactionParamsFromJson(paramsJson string) types.TxParams {
+	params := Erc20TxParams{}
+	err := json.Unmarshal([]byte(paramsJson), &params)
+	if err != nil {
+		return nil
+	}
+	return params
+}
+
+func (coin EvmChainErc721) GetCurrency() string {
+	return coin.config.Symbol + "_" + EvmTokenErc721
+}
+
+func (coin EvmChainErc721) GetEmptyTransactionParams() types.TxParams {
+	return Erc721TxParams{}
+}
+
+func (coin EvmChainErc721) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
+	if err := coin.checkFees(params.(Erc721TxParams).EthTxParams); err != nil {
+		return nil, err
+	}
+	return createErc721TokenTransaction(params, NetworkFromTestNet(coin.GetCurrency(), testNet).ChainID)
+}
+
+func (coin EvmChainErc721) GetTransactionParamsFromJson(paramsJson string) types.TxParams {
+	params := Erc721TxParams{}
+	err := json.Unmarshal([]byte(paramsJson), &params)
+	if err != nil {
+		return nil
+	}
+	return params
+}
+
+func (coin EvmChainErc1155) GetCurrency() string {
+	return coin.config.Symbol + "_" + EvmTokenErc1155
+}
+
+func (coin EvmChainErc1155) GetEmptyTransactionParams() types.TxParams {
+	return Erc1155TxParams{}
+}
+
+func (coin EvmChainErc1155) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
+	if err := coin.checkFees(params.(Erc1155TxParams).EthTxParams); err != nil {
+		return nil, err
+	}
+	return createErc1155TokenTransaction(params, NetworkFromTestNet(coin.GetCurrency(), testNet).ChainID)
+}
+
+func (coin EvmChainErc1155) GetTransactionParamsFromJson(paramsJson string) types.TxParams {
+	params := Erc1155TxParams{}
+	err := json.Unmarshal([]byte(paramsJson), &params)
+	if err != nil {
+		return nil
+	}
+	return params
+}
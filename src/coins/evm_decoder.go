@@ -0,0 +1,288 @@
+package coins
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/shopspring/decimal"
+)
+
+// abis of the calls decoded out of the box
+const (
+	erc20DecoderAbi = `[
+{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]},
+{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}]},
+{"type":"function","name":"transferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"amount","type":"uint256"}]},
+{"type":"function","name":"increaseAllowance","inputs":[{"name":"spender","type":"address"},{"name":"addedValue","type":"uint256"}]},
+{"type":"function","name":"decreaseAllowance","inputs":[{"name":"spender","type":"address"},{"name":"subtractedValue","type":"uint256"}]}]`
+
+	erc721DecoderAbi = `[
+{"type":"function","name":"safeTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}]},
+{"type":"function","name":"safeTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"},{"name":"data","type":"bytes"}]},
+{"type":"function","name":"setApprovalForAll","inputs":[{"name":"operator","type":"address"},{"name":"approved","type":"bool"}]}]`
+
+	erc1155DecoderAbi = `[
+{"type":"function","name":"safeTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"id","type":"uint256"},{"name":"amount","type":"uint256"},{"name":"data","type":"bytes"}]},
+{"type":"function","name":"safeBatchTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"ids","type":"uint256[]"},{"name":"amounts","type":"uint256[]"},{"name":"data","type":"bytes"}]}]`
+
+	permitDecoderAbi = `[
+{"type":"function","name":"permit","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"},{"name":"value","type":"uint256"},{"name":"deadline","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}]},
+{"type":"function","name":"permit","inputs":[{"name":"holder","type":"address"},{"name":"spender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"expiry","type":"uint256"},{"name":"allowed","type":"bool"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}]},
+{"type":"function","name":"permit","inputs":[{"name":"owner","type":"address"},{"name":"permitSingle","type":"tuple","components":[{"name":"details","type":"tuple","components":[{"name":"token","type":"address"},{"name":"amount","type":"uint160"},{"name":"expiration","type":"uint48"},{"name":"nonce","type":"uint48"}]},{"name":"spender","type":"address"},{"name":"sigDeadline","type":"uint256"}]},{"name":"signature","type":"bytes"}]},
+{"type":"function","name":"transferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"amount","type":"uint160"},{"name":"token","type":"address"}]},
+{"type":"function","name":"transferWithAuthorization","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"validAfter","type":"uint256"},{"name":"validBefore","type":"uint256"},{"name":"nonce","type":"bytes32"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}]},
+{"type":"function","name":"receiveWithAuthorization","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"validAfter","type":"uint256"},{"name":"validBefore","type":"uint256"},{"name":"nonce","type":"bytes32"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}]}]`
+
+	multicallDecoderAbi = `[
+{"type":"function","name":"aggregate","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"callData","type":"bytes"}]}]},
+{"type":"function","name":"tryAggregate","inputs":[{"name":"requireSuccess","type":"bool"},{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"callData","type":"bytes"}]}]},
+{"type":"function","name":"aggregate3","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}]},
+{"type":"function","name":"aggregate3Value","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"value","type":"uint256"},{"name":"callData","type":"bytes"}]}]},
+{"type":"function","name":"multicall","inputs":[{"name":"data","type":"bytes[]"}]},
+{"type":"function","name":"multicall","inputs":[{"name":"deadline","type":"uint256"},{"name":"data","type":"bytes[]"}]}]`
+)
+
+// EvmTxDecodeResult is a readable view of an evm transaction, amounts of the native coin are in ether units
+type EvmTxDecodeResult struct {
+	Hash                 string           `json:"hash"`
+	Type                 uint8            `json:"type"`
+	TypeName             string           `json:"typeName"`
+	ChainId              string           `json:"chainId"`
+	From                 string           `json:"from,omitempty"`
+	To                   string           `json:"to,omitempty"`
+	Nonce                uint64           `json:"nonce"`
+	Gas                  uint64           `json:"gas"`
+	GasPrice             string           `json:"gasPrice,omitempty"`
+	MaxFeePerGas         string           `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas string           `json:"maxPriorityFeePerGas,omitempty"`
+	MaxFee               decimal.Decimal  `json:"maxFee"`
+	Value                decimal.Decimal  `json:"value"`
+	Data                 string           `json:"data"`
+	AccessList           []EthAccessTuple `json:"accessList,omitempty"`
+	Action               *EvmCallAction   `json:"action,omitempty"`
+}
+
+// EvmCallAction is decoded calldata, integers are decimal strings and bytes are hex
+type EvmCallAction struct {
+	Method    string         `json:"method"`
+	Signature string         `json:"signature"`
+	Args      []EvmCallArg   `json:"args"`
+	Calls     []EvmInnerCall `json:"calls,omitempty"`
+}
+
+type EvmCallArg struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// EvmInnerCall is one call of a multicall, Target is empty for the bytes[] multicalls the contract makes to itself
+type EvmInnerCall struct {
+	Target string         `json:"target,omitempty"`
+	Data   string         `json:"data"`
+	Action *EvmCallAction `json:"action,omitempty"`
+}
+
+var evmCallDecoders = make(map[[4]byte]abi.Method, 0)
+
+// evmCallDecodersLock lets decoders be registered while transactions are decoded
+var evmCallDecodersLock sync.RWMutex
+
+func init() {
+	for _, abiJson := range []string{erc20DecoderAbi, erc721DecoderAbi, erc1155DecoderAbi, permitDecoderAbi, multicallDecoderAbi} {
+		if err := RegisterEvmCallDecoder(abiJson); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterEvmCallDecoder adds the methods of an ABI to the calldata decoders,
+// replacing the methods with the same selector
+func RegisterEvmCallDecoder(abiJson string) error {
+	parsed, err := abi.JSON(strings.NewReader(abiJson))
+	if err != nil {
+		return err
+	}
+	evmCallDecodersLock.Lock()
+	defer evmCallDecodersLock.Unlock()
+	for _, method := range parsed.Methods {
+		var selector [4]byte
+		copy(selector[:], method.ID)
+		evmCallDecoders[selector] = method
+	}
+	return nil
+}
+
+// DecodeEvmTx decodes a raw transaction, decimals are the decimals of the native coin
+func DecodeEvmTx(rawTx string, decimals int) (*EvmTxDecodeResult, error) {
+	tx, err := DecodeTx(rawTx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := EvmTxDecodeResult{
+		Hash:     tx.Hash().Hex(),
+		Type:     tx.Type(),
+		TypeName: evmTxTypeName(tx.Type()),
+		ChainId:  tx.ChainId().String(),
+		Nonce:    tx.Nonce(),
+		Gas:      tx.Gas(),
+		MaxFee:   decimal.NewFromBigInt(new(big.Int).Mul(tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas())), int32(-decimals)),
+		Value:    decimal.NewFromBigInt(tx.Value(), int32(-decimals)),
+		Data:     hexutil.Encode(tx.Data()),
+		Action:   DecodeEvmCall(tx.Data()),
+	}
+	if tx.To() != nil {
+		result.To = tx.To().Hex()
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		result.MaxFeePerGas = tx.GasFeeCap().String()
+		result.MaxPriorityFeePerGas = tx.GasTipCap().String()
+	} else {
+		result.GasPrice = tx.GasPrice().String()
+	}
+	for _, tuple := range tx.AccessList() {
+		storageKeys := make([]string, 0, len(tuple.StorageKeys))
+		for _, key := range tuple.StorageKeys {
+			storageKeys = append(storageKeys, key.Hex())
+		}
+		result.AccessList = append(result.AccessList, EthAccessTuple{Address: tuple.Address.Hex(), StorageKeys: storageKeys})
+	}
+
+	if isSignedEvmTx(tx) {
+		from, err := evmTxSender(tx)
+		if err != nil {
+			return nil, err
+		}
+		result.From = from.Hex()
+	}
+	return &result, nil
+}
+
+func isSignedEvmTx(tx *types.Transaction) bool {
+	_, r, _ := tx.RawSignatureValues()
+	return r != nil && r.Sign() != 0
+}
+
+// evmTxSender recovers the sender of a signed transaction, with or without replay protection
+func evmTxSender(tx *types.Transaction) (common.Address, error) {
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.LatestSignerForChainID(tx.ChainId())
+	}
+	return types.Sender(signer, tx)
+}
+
+// DecodeEvmCall decodes calldata with the registered decoders, nil when the selector is unknown
+// or the arguments don't match the method
+func DecodeEvmCall(data []byte) *EvmCallAction {
+	if len(data) < 4 {
+		return nil
+	}
+	var selector [4]byte
+	copy(selector[:], data[:4])
+	evmCallDecodersLock.RLock()
+	method, ok := evmCallDecoders[selector]
+	evmCallDecodersLock.RUnlock()
+	if !ok {
+		return nil
+	}
+	values, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil
+	}
+
+	action := EvmCallAction{Method: method.RawName, Signature: method.Sig}
+	for i, input := range method.Inputs {
+		value := reflect.ValueOf(values[i])
+		action.Args = append(action.Args, EvmCallArg{Name: input.Name, Type: input.Type.String(), Value: formatAbiValue(input.Type, value)})
+		action.Calls = append(action.Calls, innerCalls(input.Type, value)...)
+	}
+	return &action
+}
+
+// innerCalls extracts the calls of multicall arguments, Multicall3 style (target, callData) tuples and bytes[]
+func innerCalls(t abi.Type, value reflect.Value) []EvmInnerCall {
+	if t.T != abi.SliceTy {
+		return nil
+	}
+	var calls []EvmInnerCall
+	if t.Elem.T == abi.BytesTy {
+		for i := 0; i < value.Len(); i++ {
+			data := value.Index(i).Bytes()
+			calls = append(calls, EvmInnerCall{Data: hexutil.Encode(data), Action: DecodeEvmCall(data)})
+		}
+		return calls
+	}
+	if t.Elem.T != abi.TupleTy {
+		return nil
+	}
+	targetIndex, dataIndex := -1, -1
+	for i, name := range t.Elem.TupleRawNames {
+		switch {
+		case name == "target" && t.Elem.TupleElems[i].T == abi.AddressTy:
+			targetIndex = i
+		case name == "callData" && t.Elem.TupleElems[i].T == abi.BytesTy:
+			dataIndex = i
+		}
+	}
+	if targetIndex < 0 || dataIndex < 0 {
+		return nil
+	}
+	for i := 0; i < value.Len(); i++ {
+		target := value.Index(i).Field(targetIndex).Interface().(common.Address)
+		data := value.Index(i).Field(dataIndex).Bytes()
+		calls = append(calls, EvmInnerCall{Target: target.Hex(), Data: hexutil.Encode(data), Action: DecodeEvmCall(data)})
+	}
+	return calls
+}
+
+func formatAbiValue(t abi.Type, value reflect.Value) interface{} {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		if n, ok := value.Interface().(*big.Int); ok {
+			return n.String()
+		}
+		return fmt.Sprint(value.Interface())
+	case abi.AddressTy:
+		return value.Interface().(common.Address).Hex()
+	case abi.BytesTy:
+		return hexutil.Encode(value.Bytes())
+	case abi.FixedBytesTy, abi.FunctionTy:
+		b := make([]byte, value.Len())
+		reflect.Copy(reflect.ValueOf(b), value)
+		return hexutil.Encode(b)
+	case abi.SliceTy, abi.ArrayTy:
+		elems := make([]interface{}, value.Len())
+		for i := range elems {
+			elems[i] = formatAbiValue(*t.Elem, value.Index(i))
+		}
+		return elems
+	case abi.TupleTy:
+		fields := make(map[string]interface{}, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			fields[t.TupleRawNames[i]] = formatAbiValue(*elem, value.Field(i))
+		}
+		return fields
+	}
+	return value.Interface()
+}
+
+func evmTxTypeName(txType uint8) string {
+	switch txType {
+	case types.LegacyTxType:
+		return "legacy"
+	case types.AccessListTxType:
+		return "accessList"
+	case types.DynamicFeeTxType:
+		return "dynamicFee"
+	}
+	return fmt.Sprintf("0x%x", txType)
+}
@@ -17,6 +17,7 @@ var coinFtm Ftm
 func init() {
 	coinFtm = Ftm{}
 	RegisterEthLikeCoin(coinFtm)
+	registerEvmNetworks(CurrencyFtm, FTM_MAIN, FTM_TEST)
 }
 
 type Ftm struct {
@@ -23,7 +23,7 @@ func (coin FtmErc20) GetCurrency() string {
 }
 
 func (coin FtmErc20) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
-	return createTokenTransaction(params)
+	return createTokenTransaction(params, NetworkFromTestNet(coin.GetCurrency(), testNet).ChainID)
 }
 
 func (coin FtmErc20) GetTransactionParamsFromJson(paramsJson string) types.TxParams {
@@ -18,6 +18,7 @@ func init() {
 	coinHt = Ht{}
 	RegisterEthLikeCoin(coinHt)
 
+	registerEvmNetworks(CurrencyHt, HT_MAIN, HT_TEST)
 }
 
 type Ht struct {
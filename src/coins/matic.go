@@ -7,7 +7,7 @@ import (
 )
 
 const (
-	MATIC_TEST    = 80001
+	MATIC_TEST    = 80002 // Amoy
 	MATIC_MAIN    = 137
 	CurrencyMatic = "MATIC"
 )
@@ -18,6 +18,7 @@ func init() {
 	coinMatic = Matic{}
 
 	RegisterEthLikeCoin(coinMatic)
+	registerEvmNetworks(CurrencyMatic, MATIC_MAIN, MATIC_TEST)
 }
 
 type Matic struct {
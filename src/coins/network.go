@@ -0,0 +1,165 @@
+package coins
+
+import (
+	"math/big"
+	"strings"
+	"wallet-sdk/src/errors"
+	"wallet-sdk/src/types"
+
+	"github.com/btcsuite/btcd/chaincfg"
+)
+
+type NetworkKind string
+
+const (
+	NetworkMainNet NetworkKind = "mainnet"
+	NetworkTestNet NetworkKind = "testnet"
+	// NetworkDevNet is a local or private network, such as a bitcoin regtest or an evm devnet
+	NetworkDevNet NetworkKind = "devnet"
+)
+
+// Network describes one network of a coin. Only the fields the coin needs are set:
+// ChainParams for bitcoin-like coins, ChainID for evm coins, AddressPrefix for ss58 coins.
+type Network struct {
+	ID          string           `json:"id"`
+	Kind        NetworkKind      `json:"kind"`
+	ChainParams *chaincfg.Params `json:"-"`
+	ChainID     *big.Int         `json:"chainId,omitempty"`
+	// AddressPrefix is the ss58 address prefix
+	AddressPrefix uint8 `json:"addressPrefix,omitempty"`
+	// Decimals overrides the decimals of the coin on this network when not 0
+	Decimals int `json:"decimals,omitempty"`
+}
+
+// IsTestNet reports whether the network is anything but a mainnet, as the legacy testNet flag does
+func (network Network) IsTestNet() bool {
+	return network.Kind != NetworkMainNet
+}
+
+// NetworkAddressGenerator is implemented by coins whose addresses depend on the network
+type NetworkAddressGenerator interface {
+	GenerateAddressOnNetwork(privateKey types.PrivateKey, network Network) (*types.CoinAddress, error)
+}
+
+// NetworkTransactionCreator is implemented by coins whose transactions depend on the network
+type NetworkTransactionCreator interface {
+	CreateTransactionOnNetwork(params types.TxParams, network Network) (*types.BaseTransaction, error)
+}
+
+// NetworkSigner is implemented by coins whose signatures depend on the network
+type NetworkSigner interface {
+	SignTxOnNetwork(baseTransaction *types.BaseTransaction, network Network, privateKey types.PrivateKey) (*string, error)
+}
+
+var coinNetworks = make(map[string][]Network, 0)
+
+// RegisterNetwork Add a network to a currency, replacing the network with the same id
+func RegisterNetwork(currency string, network Network) {
+	currency = strings.ToUpper(currency)
+	for i := range coinNetworks[currency] {
+		if coinNetworks[currency][i].ID == network.ID {
+			coinNetworks[currency][i] = network
+			return
+		}
+	}
+	coinNetworks[currency] = append(coinNetworks[currency], network)
+}
+
+// GetNetworks Get the networks of a currency, tokens share the networks of their chain
+func GetNetworks(currency string) []Network {
+	currency = strings.ToUpper(currency)
+	if networks := coinNetworks[currency]; len(networks) > 0 {
+		return networks
+	}
+	coin, err := GetCoin(currency)
+	if err != nil {
+		return nil
+	}
+	return coinNetworks[strings.ToUpper(coin.ChainName())]
+}
+
+// GetNetwork Get a network of a currency by its id
+func GetNetwork(currency string, id string) (Network, error) {
+	for _, network := range GetNetworks(currency) {
+		if network.ID == id {
+			return network, nil
+		}
+	}
+	return Network{}, errors.ErrorNetworkNotSupported
+}
+
+// NetworkFromTestNet maps the legacy testNet flag to the first registered network of that kind
+func NetworkFromTestNet(currency string, testNet bool) Network {
+	kind := NetworkMainNet
+	if testNet {
+		kind = NetworkTestNet
+	}
+	for _, network := range GetNetworks(currency) {
+		if network.Kind == kind {
+			return network
+		}
+	}
+	return Network{ID: string(kind), Kind: kind}
+}
+
+// GenerateAddressOnNetwork Generate an address of coin on network
+func GenerateAddressOnNetwork(coin Coin, privateKey types.PrivateKey, network Network) (*types.CoinAddress, error) {
+	useNetwork, err := isNetworkOf(coin, network)
+	if err != nil {
+		return nil, err
+	}
+	if generator, ok := coin.(NetworkAddressGenerator); ok && useNetwork {
+		return generator.GenerateAddressOnNetwork(privateKey, network)
+	}
+	return coin.GenerateAddress(privateKey, network.IsTestNet())
+}
+
+// CreateTransactionOnNetwork Create a raw transaction of coin on network
+func CreateTransactionOnNetwork(coin Coin, params types.TxParams, network Network) (*types.BaseTransaction, error) {
+	useNetwork, err := isNetworkOf(coin, network)
+	if err != nil {
+		return nil, err
+	}
+	if creator, ok := coin.(NetworkTransactionCreator); ok && useNetwork {
+		return creator.CreateTransactionOnNetwork(params, network)
+	}
+	return coin.CreateTransaction(params, network.IsTestNet())
+}
+
+// SignTxOnNetwork Sign a transaction of coin on network
+func SignTxOnNetwork(coin Coin, baseTransaction *types.BaseTransaction, network Network, privateKey types.PrivateKey) (*string, error) {
+	useNetwork, err := isNetworkOf(coin, network)
+	if err != nil {
+		return nil, err
+	}
+	if signer, ok := coin.(NetworkSigner); ok && useNetwork {
+		return signer.SignTxOnNetwork(baseTransaction, network, privateKey)
+	}
+	return coin.SignTx(baseTransaction, network.IsTestNet(), privateKey)
+}
+
+// EvmChainId returns the chain id of an evm coin on network
+func EvmChainId(coin Coin, network Network) (*big.Int, error) {
+	if _, err := isNetworkOf(coin, network); err != nil {
+		return nil, err
+	}
+	if network.ChainID == nil {
+		return nil, errors.ErrorNetworkNotSupported
+	}
+	return network.ChainID, nil
+}
+
+// isNetworkOf reports whether network is registered for coin. Coins without registered
+// networks only know the testNet flag, for them any network falls back to it.
+func isNetworkOf(coin Coin, network Network) (bool, error) {
+	networks := GetNetworks(coin.GetCurrency())
+	if len(networks) == 0 {
+		return false, nil
+	}
+	for _, registered := range networks {
+		if registered.ID == network.ID {
+			return true, nil
+		}
+	}
+	return false, errors.ErrorNetworkNotSupported
+}
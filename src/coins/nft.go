@@ -0,0 +1,177 @@
+package coins
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"wallet-sdk/src/errors"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// NftMethod is the call of an ERC-721 or ERC-1155 transaction, safeTransferFrom when empty
+type NftMethod string
+
+const (
+	NftSafeTransferFrom NftMethod = "safeTransferFrom"
+	// NftTransferFrom skips the onERC721Received check of the recipient, ERC-721 only
+	NftTransferFrom NftMethod = "transferFrom"
+	// NftApprove lets ToAddress transfer TokenId, ERC-721 only
+	NftApprove NftMethod = "approve"
+	// NftRevokeApproval clears the approved address of TokenId, ERC-721 only
+	NftRevokeApproval NftMethod = "revokeApproval"
+	// NftSetApprovalForAll lets the operator ToAddress transfer every token of the owner
+	NftSetApprovalForAll NftMethod = "setApprovalForAll"
+	// NftRevokeApprovalForAll removes the operator ToAddress, as marketplaces are revoked
+	NftRevokeApprovalForAll NftMethod = "revokeApprovalForAll"
+)
+
+// MaxErc1155BatchSize is the most tokens of a safeBatchTransferFrom
+const MaxErc1155BatchSize = 200
+
+const (
+	erc721Abi = `[
+{"type":"function","name":"safeTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}]},
+{"type":"function","name":"safeTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"},{"name":"data","type":"bytes"}]},
+{"type":"function","name":"transferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}]},
+{"type":"function","name":"approve","inputs":[{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}]},
+{"type":"function","name":"setApprovalForAll","inputs":[{"name":"operator","type":"address"},{"name":"approved","type":"bool"}]}]`
+
+	erc1155Abi = `[
+{"type":"function","name":"safeTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"id","type":"uint256"},{"name":"amount","type":"uint256"},{"name":"data","type":"bytes"}]},
+{"type":"function","name":"safeBatchTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"ids","type":"uint256[]"},{"name":"amounts","type":"uint256[]"},{"name":"data","type":"bytes"}]},
+{"type":"function","name":"setApprovalForAll","inputs":[{"name":"operator","type":"address"},{"name":"approved","type":"bool"}]}]`
+)
+
+var erc721Methods, erc1155Methods abi.ABI
+
+func init() {
+	var err error
+	if erc721Methods, err = abi.JSON(strings.NewReader(erc721Abi)); err != nil {
+		panic(err)
+	}
+	if erc1155Methods, err = abi.JSON(strings.NewReader(erc1155Abi)); err != nil {
+		panic(err)
+	}
+}
+
+// encodeErc721Call returns the calldata of the method of params
+func encodeErc721Call(params Erc721TxParams, chainId *big.Int) ([]byte, error) {
+	tokenId := &params.TokenId.Int
+	if tokenId.Sign() < 0 || tokenId.BitLen() > 256 {
+		return nil, fmt.Errorf("%w: invalid token id", errors.ErrorInvalidInput)
+	}
+	transferData, err := decodeNftTransferData(params.Method, params.TransferData)
+	if err != nil {
+		return nil, err
+	}
+	switch params.Method {
+	case "", NftSafeTransferFrom, NftTransferFrom:
+		from, to, err := nftTransferAddresses(params.FromAddress, params.ToAddress, chainId)
+		if err != nil {
+			return nil, err
+		}
+		if params.Method == NftTransferFrom {
+			return packAbiMethod(erc721Methods.Methods["transferFrom"], from, to, tokenId)
+		}
+		if len(transferData) == 0 {
+			return packAbiMethod(erc721Methods.Methods["safeTransferFrom"], from, to, tokenId)
+		}
+		return packAbiMethod(erc721Methods.Methods["safeTransferFrom0"], from, to, tokenId, transferData)
+	case NftApprove:
+		if err := ValidateAddressOnChain(params.ToAddress, chainId); err != nil {
+			return nil, err
+		}
+		return packAbiMethod(erc721Methods.Methods["approve"], common.HexToAddress(params.ToAddress), tokenId)
+	case NftRevokeApproval:
+		return packAbiMethod(erc721Methods.Methods["approve"], common.Address{}, tokenId)
+	case NftSetApprovalForAll, NftRevokeApprovalForAll:
+		return encodeSetApprovalForAll(erc721Methods, params.Method, params.ToAddress, chainId)
+	}
+	return nil, fmt.Errorf("%w: unknown erc721 method %s", errors.ErrorInvalidInput, params.Method)
+}
+
+// encodeErc1155Call returns the calldata of the method of params, a transfer of several tokens is a safeBatchTransferFrom
+func encodeErc1155Call(params Erc1155TxParams, chainId *big.Int) ([]byte, error) {
+	transferData, err := decodeNftTransferData(params.Method, params.TransferData)
+	if err != nil {
+		return nil, err
+	}
+	switch params.Method {
+	case "", NftSafeTransferFrom:
+		from, to, err := nftTransferAddresses(params.FromAddress, params.ToAddress, chainId)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateErc1155BatchData(params.BatchData); err != nil {
+			return nil, err
+		}
+		if len(params.BatchData) == 1 {
+			tokenData := params.BatchData[0]
+			return packAbiMethod(erc1155Methods.Methods["safeTransferFrom"], from, to, &tokenData.TokenId.Int, &tokenData.Amount.Int, transferData)
+		}
+		var tokenIds []*big.Int
+		var amounts []*big.Int
+		for index := range params.BatchData {
+			tokenIds = append(tokenIds, &params.BatchData[index].TokenId.Int)
+			amounts = append(amounts, &params.BatchData[index].Amount.Int)
+		}
+		return packAbiMethod(erc1155Methods.Methods["safeBatchTransferFrom"], from, to, tokenIds, amounts, transferData)
+	case NftSetApprovalForAll, NftRevokeApprovalForAll:
+		return encodeSetApprovalForAll(erc1155Methods, params.Method, params.ToAddress, chainId)
+	}
+	return nil, fmt.Errorf("%w: unknown erc1155 method %s", errors.ErrorInvalidInput, params.Method)
+}
+
+// validateErc1155BatchData checks the tokens of a transfer, ids fit an uint256 and amounts are positive
+func validateErc1155BatchData(batchData []Erc1155BatchData) error {
+	if len(batchData) == 0 {
+		return fmt.Errorf("%w: no token data", errors.ErrorInvalidInput)
+	}
+	if len(batchData) > MaxErc1155BatchSize {
+		return fmt.Errorf("%w: %d tokens, at most %d", errors.ErrorInvalidInput, len(batchData), MaxErc1155BatchSize)
+	}
+	for _, tokenData := range batchData {
+		if tokenData.TokenId.Sign() < 0 || tokenData.TokenId.BitLen() > 256 {
+			return fmt.Errorf("%w: invalid token id %s", errors.ErrorInvalidInput, tokenData.TokenId.String())
+		}
+		if tokenData.Amount.Sign() <= 0 || tokenData.Amount.BitLen() > 256 {
+			return fmt.Errorf("%w: token %s", errors.ErrorInvalidAmount, tokenData.TokenId.String())
+		}
+	}
+	return nil
+}
+
+func encodeSetApprovalForAll(methods abi.ABI, method NftMethod, operator string, chainId *big.Int) ([]byte, error) {
+	if err := ValidateAddressOnChain(operator, chainId); err != nil {
+		return nil, err
+	}
+	return packAbiMethod(methods.Methods["setApprovalForAll"], common.HexToAddress(operator), method == NftSetApprovalForAll)
+}
+
+func nftTransferAddresses(fromAddress string, toAddress string, chainId *big.Int) (common.Address, common.Address, error) {
+	if err := ValidateAddressOnChain(fromAddress, chainId); err != nil {
+		return common.Address{}, common.Address{}, errors.ErrorInvalidSendAddress
+	}
+	if err := ValidateAddressOnChain(toAddress, chainId); err != nil {
+		return common.Address{}, common.Address{}, err
+	}
+	return common.HexToAddress(fromAddress), common.HexToAddress(toAddress), nil
+}
+
+// decodeNftTransferData decodes the data passed to the receiver hook, only a safeTransferFrom takes it
+func decodeNftTransferData(method NftMethod, transferData string) ([]byte, error) {
+	if transferData == "" {
+		return []byte{}, nil
+	}
+	if method != "" && method != NftSafeTransferFrom {
+		return nil, fmt.Errorf("%w: %s takes no data", errors.ErrorInvalidInput, method)
+	}
+	data, err := hexutil.Decode(transferData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrorInvalidInput, err)
+	}
+	return data, nil
+}
@@ -17,6 +17,7 @@ var coinOkt Okt
 func init() {
 	coinOkt = Okt{}
 	RegisterEthLikeCoin(coinOkt)
+	registerEvmNetworks(CurrencyOkt, OKT_MAIN, OKT_TEST)
 }
 
 type Okt struct {
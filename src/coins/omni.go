@@ -1,25 +1,84 @@
 package coins
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
 	"github.com/btcsuite/btcd/wire"
 	"github.com/btcsuite/btcwallet/wallet/txauthor"
 	"github.com/shopspring/decimal"
 	"strconv"
+	"sync"
 	"wallet-sdk/src/errors"
 	"wallet-sdk/src/types"
 )
 
 const CurrencyOmni = "OMNI"
 
+// OmniTxParams sends Amount of the property ContractAddress (the property id)
+// to ToAddress. Divisible defaults to true, SendAll sends every token of the
+// Ecosystem instead.
 type OmniTxParams struct {
 	BtcTxParams
 	Amount          decimal.Decimal `json:"amount"`
 	ToAddress       string          `json:"toAddress"`
 	ContractAddress string          `json:"commonContractAddress"`
+	Divisible       *bool           `json:"divisible,omitempty"`
+	SendAll         bool            `json:"sendAll"`
+	Ecosystem       uint8           `json:"ecosystem"`
+}
+
+// omniDivisibleProperties lists the divisibility of known properties, it
+// is used to format decoded amounts
+var omniDivisibleProperties = map[uint32]bool{
+	1:  true,  // OMNI
+	2:  true,  // TOMNI
+	3:  false, // MaidSafeCoin
+	31: true,  // TetherUS
+}
+
+var omniPropertiesLock sync.RWMutex
+
+// RegisterOmniProperty sets the divisibility of a property, decoded amounts of known properties are formatted
+func RegisterOmniProperty(propertyId uint32, divisible bool) {
+	omniPropertiesLock.Lock()
+	defer omniPropertiesLock.Unlock()
+	omniDivisibleProperties[propertyId] = divisible
+}
+
+var omniTypeNames = map[uint16]string{
+	OmniTypeSimpleSend: "Simple Send",
+	3:                  "Send To Owners",
+	OmniTypeSendAll:    "Send All",
+	7:                  "Send To Many",
+	50:                 "Create Property - Fixed",
+	51:                 "Create Property - Variable",
+	54:                 "Create Property - Manual",
+	55:                 "Grant Property Tokens",
+	56:                 "Revoke Property Tokens",
+}
+
+// OmniPayload is a decoded Omni Layer class C payload
+type OmniPayload struct {
+	Version    uint16 `json:"version"`
+	Type       uint16 `json:"type"`
+	TypeName   string `json:"typeName,omitempty"`
+	PropertyID uint32 `json:"propertyId,omitempty"`
+	Ecosystem  uint8  `json:"ecosystem,omitempty"`
+	// AmountRaw is in the smallest unit of the property, Amount is only set
+	// when the divisibility of the property is known
+	AmountRaw        int64  `json:"amountRaw,omitempty"`
+	Amount           string `json:"amount,omitempty"`
+	Sender           string `json:"sender,omitempty"`
+	ReferenceAddress string `json:"referenceAddress,omitempty"`
+	Payload          string `json:"payload"`
 }
 
 var coinOmni Omni
@@ -52,13 +111,45 @@ func (coin Omni) GetTransactionParamsFromJson(paramsJson string) types.TxParams
 }
 
 func (coin Omni) CreateTransaction(txParams types.TxParams, testNet bool) (*types.BaseTransaction, error) {
+	return coin.createTransaction(txParams, coin.GetNetParams(testNet))
+}
+
+func (coin Omni) CreateTransactionOnNetwork(txParams types.TxParams, network Network) (*types.BaseTransaction, error) {
+	params, err := networkChainParams(network)
+	if err != nil {
+		return nil, err
+	}
+	return coin.createTransaction(txParams, params)
+}
+
+func (coin Omni) createTransaction(txParams types.TxParams, params chaincfg.Params) (*types.BaseTransaction, error) {
 	extraParams := txParams.(OmniTxParams)
 	var unspends = extraParams.Unspends
 	contractAddress := extraParams.ContractAddress
-	var propertyID, _ = strconv.ParseUint(contractAddress, 10, 32)
+	divisible := extraParams.Divisible == nil || *extraParams.Divisible
+	var opreturnScript []byte
+	var err error
+	if extraParams.SendAll {
+		ecosystem := extraParams.Ecosystem
+		if ecosystem == 0 {
+			ecosystem = OmniEcosystemMain
+		}
+		opreturnScript, err = GetClassCSendAllOpreturnDataScript(ecosystem)
+	} else {
+		propertyID, parseErr := strconv.ParseUint(contractAddress, 10, 32)
+		if parseErr != nil {
+			return nil, errors.ErrorInvalidContractAddress
+		}
+		if !divisible && !extraParams.Amount.IsInteger() {
+			return nil, errors.ErrorInvalidAmount
+		}
+		opreturnScript, err = GetClassCOpreturnDataScript(uint(propertyID), extraParams.Amount.InexactFloat64(), divisible)
+	}
+	if err != nil {
+		return nil, err
+	}
 	changeAddress := extraParams.ChangeAddress
 	var totalAmount = btcutil.Amount(0)
-	params := coin.GetNetParams(testNet)
 	var currentInputs []*wire.TxIn
 	var currentInputValues []btcutil.Amount
 	var inputScripts [][]byte
@@ -107,18 +198,13 @@ func (coin Omni) CreateTransaction(txParams types.TxParams, testNet bool) (*type
 	if err != nil {
 		return nil, err
 	}
+	opreturnTxOut := wire.NewTxOut(0, opreturnScript)
+	txOut = append(txOut, opreturnTxOut)
 	txOut = append(txOut, &wire.TxOut{
 		Value:    int64(MinNondustOutput),
 		PkScript: script,
 	})
 
-	opreturnScript, err := GetClassCOpreturnDataScript(uint(propertyID), extraParams.Amount.InexactFloat64(), true)
-	if err != nil {
-		return nil, err
-	}
-	opreturnTxOut := wire.NewTxOut(0, opreturnScript)
-	txOut = append(txOut, opreturnTxOut)
-
 	need.Add(decimal.NewFromFloat(extraParams.Fee.InexactFloat64()))
 	if err != nil {
 		return nil, err
@@ -149,6 +235,101 @@ func (coin Omni) CreateTransaction(txParams types.TxParams, testNet bool) (*type
 	if err != nil {
 		return nil, err
 	}
+	// the reference output is the last output not paying the sender, move it
+	// behind the change which may go to another address
+	if changeIndex := unsignedTransaction.ChangeIndex; changeIndex >= 0 {
+		outs := unsignedTransaction.Tx.TxOut
+		outs[changeIndex-1], outs[changeIndex] = outs[changeIndex], outs[changeIndex-1]
+		unsignedTransaction.ChangeIndex = changeIndex - 1
+	}
 
 	return &types.BaseTransaction{CoinTransaction: unsignedTransaction}, nil
 }
+
+// decodeOmniPayload decodes the class C payload of mtx, if any. The sender is
+// the address of the first input, and the reference address the last output
+// not paying the sender.
+func decodeOmniPayload(mtx *wire.MsgTx, vouts []btcjson.Vout, params *chaincfg.Params) *OmniPayload {
+	var data []byte
+	for _, out := range mtx.TxOut {
+		if txscript.GetScriptClass(out.PkScript) != txscript.NullDataTy {
+			continue
+		}
+		pushes, err := txscript.PushedData(out.PkScript)
+		if err == nil && len(pushes) == 1 && bytes.HasPrefix(pushes[0], []byte("omni")) {
+			data = pushes[0][4:]
+			break
+		}
+	}
+	if len(data) < 4 {
+		return nil
+	}
+
+	payload := &OmniPayload{
+		Version: binary.BigEndian.Uint16(data[0:2]),
+		Type:    binary.BigEndian.Uint16(data[2:4]),
+		Payload: hex.EncodeToString(data),
+	}
+	payload.TypeName = omniTypeNames[payload.Type]
+	switch {
+	case payload.Type == OmniTypeSimpleSend && len(data) >= 16:
+		payload.PropertyID = binary.BigEndian.Uint32(data[4:8])
+		payload.AmountRaw = int64(binary.BigEndian.Uint64(data[8:16]))
+		omniPropertiesLock.RLock()
+		divisible, ok := omniDivisibleProperties[payload.PropertyID]
+		omniPropertiesLock.RUnlock()
+		if ok {
+			if divisible {
+				payload.Amount = decimal.New(payload.AmountRaw, -8).String()
+			} else {
+				payload.Amount = strconv.FormatInt(payload.AmountRaw, 10)
+			}
+		}
+	case payload.Type == OmniTypeSendAll && len(data) >= 5:
+		payload.Ecosystem = data[4]
+	}
+
+	if len(mtx.TxIn) > 0 {
+		payload.Sender = omniSenderAddress(mtx.TxIn[0], params)
+	}
+	for _, vout := range vouts {
+		addrs := vout.ScriptPubKey.Addresses
+		if len(addrs) == 1 && addrs[0] != payload.Sender {
+			payload.ReferenceAddress = addrs[0]
+		}
+	}
+	if payload.ReferenceAddress == "" {
+		payload.ReferenceAddress = payload.Sender
+	}
+	return payload
+}
+
+// omniSenderAddress derives the address spent by a signed p2pkh, p2wpkh or p2sh-p2wpkh input
+func omniSenderAddress(txIn *wire.TxIn, params *chaincfg.Params) string {
+	var pubKey []byte
+	if len(txIn.Witness) == 2 {
+		pubKey = txIn.Witness[1]
+	} else if pushes, err := txscript.PushedData(txIn.SignatureScript); err == nil && len(pushes) == 2 {
+		pubKey = pushes[1]
+	}
+	if _, err := btcec.ParsePubKey(pubKey); err != nil {
+		return ""
+	}
+
+	hash := btcutil.Hash160(pubKey)
+	var addr btcutil.Address
+	var err error
+	switch {
+	case len(txIn.Witness) == 2 && len(txIn.SignatureScript) > 0:
+		witnessProgram, _ := txscript.NewScriptBuilder().AddOp(txscript.OP_0).AddData(hash).Script()
+		addr, err = btcutil.NewAddressScriptHash(witnessProgram, params)
+	case len(txIn.Witness) == 2:
+		addr, err = btcutil.NewAddressWitnessPubKeyHash(hash, params)
+	default:
+		addr, err = btcutil.NewAddressPubKeyHash(hash, params)
+	}
+	if err != nil {
+		return ""
+	}
+	return addr.EncodeAddress()
+}
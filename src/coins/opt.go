@@ -7,7 +7,7 @@ import (
 )
 
 const (
-	OPT_TEST    = 420
+	OPT_TEST    = 11155420 // OP Sepolia
 	OPT_MAIN    = 10
 	CurrencyOpt = "OPT"
 )
@@ -17,6 +17,7 @@ var coinOpt Opt
 func init() {
 	coinOpt = Opt{}
 	RegisterEthLikeCoin(coinOpt)
+	registerEvmNetworks(CurrencyOpt, OPT_MAIN, OPT_TEST)
 }
 
 type Opt struct {
@@ -37,6 +37,10 @@ var coinDot Dot
 func init() {
 	coinDot = Dot{}
 	RegisterCoin(coinDot)
+
+	RegisterNetwork(CurrencyDot, Network{ID: "polkadot", Kind: NetworkMainNet, AddressPrefix: 0, Decimals: 10})
+	RegisterNetwork(CurrencyDot, Network{ID: "kusama", Kind: NetworkMainNet, AddressPrefix: 2, Decimals: 12})
+	RegisterNetwork(CurrencyDot, Network{ID: "westend", Kind: NetworkTestNet, AddressPrefix: 42, Decimals: 12})
 }
 
 type Dot struct {
@@ -76,16 +80,16 @@ func (coin Dot) PrivateKeyToString(key types2.PrivateKey) (string, error) {
 }
 
 func (coin Dot) GenerateAddress(privateKey types2.PrivateKey, testNet bool) (*types2.CoinAddress, error) {
-	var err error
+	return coin.GenerateAddressOnNetwork(privateKey, NetworkFromTestNet(CurrencyDot, testNet))
+}
+
+func (coin Dot) GenerateAddressOnNetwork(privateKey types2.PrivateKey, network Network) (*types2.CoinAddress, error) {
 	scheme := sr25519.Scheme{}
 	kyr, err := scheme.FromSeed(privateKey)
-	var ss58Address string
-	if testNet {
-		ss58Address, err = kyr.SS58Address(42)
-	} else {
-		ss58Address, err = kyr.SS58Address(0)
+	if err != nil {
+		return nil, err
 	}
-
+	ss58Address, err := kyr.SS58Address(network.AddressPrefix)
 	if err != nil {
 		return nil, err
 	}
@@ -105,10 +109,15 @@ func (coin Dot) GetEmptyTransactionParams() types2.TxParams {
 }
 
 func (coin Dot) CreateTransaction(params types2.TxParams, testNet bool) (*types2.BaseTransaction, error) {
+	return coin.CreateTransactionOnNetwork(params, NetworkFromTestNet(CurrencyDot, testNet))
+}
+
+// CreateTransactionOnNetwork converts the amount with the decimals of network, polkadot has 10 and its testnets 12
+func (coin Dot) CreateTransactionOnNetwork(params types2.TxParams, network Network) (*types2.BaseTransaction, error) {
 	txParams := params.(DotTxParams)
-	var accurrency = 10
-	if testNet {
-		accurrency = 12
+	var accurrency = network.Decimals
+	if accurrency == 0 {
+		accurrency = coin.GetDecimal()
 	}
 	finalAmount := (decimal.NewFromFloat(float64(10)).Pow(decimal.NewFromFloat(float64(accurrency))).Mul(txParams.Amount)).IntPart()
 	dstAccountID, err := DotAddressToPublicKey(txParams.ToAddress)
@@ -155,16 +164,11 @@ func (coin Dot) DecodeTransaction(rawTx string, testnet bool) (interface{}, erro
 }
 
 func (coin Dot) SignTx(baseTransaction *types2.BaseTransaction, testNet bool, privateKey types2.PrivateKey) (*string, error) {
+	return coin.SignTxOnNetwork(baseTransaction, NetworkFromTestNet(CurrencyDot, testNet), privateKey)
+}
 
-	var kr signature.KeyringPair
-	var err error
-	encode := hexutil.Encode(privateKey)
-	if testNet {
-		kr, err = signature.KeyringPairFromSecret(encode, 42)
-	} else {
-		kr, err = signature.KeyringPairFromSecret(encode, 0)
-	}
-
+func (coin Dot) SignTxOnNetwork(baseTransaction *types2.BaseTransaction, network Network, privateKey types2.PrivateKey) (*string, error) {
+	kr, err := signature.KeyringPairFromSecret(hexutil.Encode(privateKey), network.AddressPrefix)
 	if err != nil {
 		return nil, err
 	}
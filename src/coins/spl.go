@@ -7,6 +7,7 @@ import (
 	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
 	"github.com/gagliardetto/solana-go/programs/token"
 	"github.com/shopspring/decimal"
+	"math/big"
 	"sort"
 	"time"
 	"wallet-sdk/src/errors"
@@ -65,6 +66,18 @@ func (coin Spl) GetTransactionParamsFromJson(paramsJson string) types.TxParams {
 
 func (coin Spl) CreateTransaction(params types.TxParams, testNet bool) (*types.BaseTransaction, error) {
 	extraParams := params.(SplTxParams)
+	// the mint is only needed to create the destination account, the source accounts name it otherwise
+	mint := extraParams.ContractAddress
+	if mint == "" && len(extraParams.FromAccounts) > 0 {
+		mint = extraParams.FromAccounts[0].MintAddress
+	}
+	tokenChainId := SolanaMainnetTokenChainId
+	if testNet {
+		tokenChainId = SolanaTestnetTokenChainId
+	}
+	if err := checkRegisteredToken(big.NewInt(tokenChainId), mint, extraParams.TokenDecimal); err != nil {
+		return nil, err
+	}
 	sourceOwnerKey, err := solana.PublicKeyFromBase58(extraParams.FromAccountOwner)
 	if err != nil {
 		return nil, errors.ErrorInvalidAddress
@@ -0,0 +1,133 @@
+package coins
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"wallet-sdk/src/errors"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// chain ids of the non-evm chains in token lists, as the Solana and Tron token lists number them.
+// The testNet flag of Spl and Trc20 selects the testnet one.
+const (
+	SolanaMainnetTokenChainId int64 = 101
+	SolanaTestnetTokenChainId int64 = 102
+	TronMainnetTokenChainId   int64 = 728126428
+	TronNileTokenChainId      int64 = 3448148188
+)
+
+// TokenInfo is a token of a token list in the Uniswap token-list schema, Address is the contract or the mint
+type TokenInfo struct {
+	ChainId  int64    `json:"chainId"`
+	Address  string   `json:"address"`
+	Name     string   `json:"name"`
+	Symbol   string   `json:"symbol"`
+	Decimals int64    `json:"decimals"`
+	LogoURI  string   `json:"logoURI,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// TokenList is a token list in the Uniswap token-list schema
+type TokenList struct {
+	Name      string      `json:"name"`
+	Timestamp string      `json:"timestamp"`
+	Tokens    []TokenInfo `json:"tokens"`
+}
+
+var registeredTokens = make(map[string]TokenInfo, 0)
+
+// strictTokenRegistry rejects the token transactions of contracts that aren't registered
+var strictTokenRegistry bool
+
+// RegisterToken adds a token to the registry, replacing the token of the same chain and address
+func RegisterToken(token TokenInfo) error {
+	if err := validateTokenInfo(token); err != nil {
+		return err
+	}
+	registeredTokens[tokenRegistryKey(token.ChainId, token.Address)] = token
+	return nil
+}
+
+func validateTokenInfo(token TokenInfo) error {
+	if token.ChainId <= 0 || token.Address == "" || token.Symbol == "" {
+		return fmt.Errorf("%w: token needs a chain id, an address and a symbol", errors.ErrorInvalidInput)
+	}
+	if token.Decimals < 0 || token.Decimals > 255 {
+		return fmt.Errorf("%w: %s has %d decimals", errors.ErrorInvalidInput, token.Symbol, token.Decimals)
+	}
+	if strings.HasPrefix(token.Address, "0x") && !common.IsHexAddress(token.Address) {
+		return fmt.Errorf("%w: %s", errors.ErrorInvalidContractAddress, token.Address)
+	}
+	return nil
+}
+
+// LoadTokenList registers the tokens of a token list JSON and returns how many it had
+func LoadTokenList(tokenListJson string) (int, error) {
+	var list TokenList
+	if err := json.Unmarshal([]byte(tokenListJson), &list); err != nil {
+		return 0, fmt.Errorf("%w: %v", errors.ErrorInvalidInput, err)
+	}
+	// check every token first, a list is loaded whole or not at all
+	for _, token := range list.Tokens {
+		if err := validateTokenInfo(token); err != nil {
+			return 0, err
+		}
+	}
+	for _, token := range list.Tokens {
+		registeredTokens[tokenRegistryKey(token.ChainId, token.Address)] = token
+	}
+	return len(list.Tokens), nil
+}
+
+// GetRegisteredToken returns the token of a contract on the chain chainId
+func GetRegisteredToken(chainId int64, address string) (TokenInfo, error) {
+	token, ok := registeredTokens[tokenRegistryKey(chainId, address)]
+	if !ok {
+		return TokenInfo{}, errors.ErrorUnknownToken
+	}
+	return token, nil
+}
+
+// TokenSymbol returns the symbol of a registered token for display, empty when it is unknown
+func TokenSymbol(chainId int64, address string) string {
+	token, _ := GetRegisteredToken(chainId, address)
+	return token.Symbol
+}
+
+// SetTokenRegistryStrict makes token transactions of unregistered contracts fail with ErrorUnknownToken,
+// registered tokens are checked either way
+func SetTokenRegistryStrict(strict bool) {
+	strictTokenRegistry = strict
+}
+
+// checkRegisteredToken rejects decimals that aren't those of a registered token, and unknown tokens in strict mode
+func checkRegisteredToken(chainId *big.Int, address string, decimals int64) error {
+	if chainId == nil || !chainId.IsInt64() {
+		if strictTokenRegistry {
+			return fmt.Errorf("%w: no chain id to look %s up", errors.ErrorUnknownToken, address)
+		}
+		return nil
+	}
+	token, err := GetRegisteredToken(chainId.Int64(), address)
+	if err != nil {
+		if strictTokenRegistry {
+			return fmt.Errorf("%w: %s on chain %s", errors.ErrorUnknownToken, address, chainId)
+		}
+		return nil
+	}
+	if token.Decimals != decimals {
+		return fmt.Errorf("%w: %s has %d decimals, not %d", errors.ErrorTokenDecimalsMismatch, token.Symbol, token.Decimals, decimals)
+	}
+	return nil
+}
+
+// tokenRegistryKey keys tokens by chain and address, evm addresses are case insensitive but base58 ones aren't
+func tokenRegistryKey(chainId int64, address string) string {
+	if common.IsHexAddress(address) {
+		address = strings.ToLower(common.HexToAddress(address).Hex())
+	}
+	return fmt.Sprintf("%d:%s", chainId, address)
+}
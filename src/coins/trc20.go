@@ -9,6 +9,7 @@ import (
 	"github.com/fbsobreira/gotron-sdk/pkg/proto/core"
 	"github.com/golang/protobuf/proto"
 	"github.com/shopspring/decimal"
+	"math/big"
 	"wallet-sdk/src/errors"
 	"wallet-sdk/src/types"
 )
@@ -50,6 +51,13 @@ func (coin Trc20) CreateTransaction(params types.TxParams, testNet bool) (*types
 	extraParams := params.(Trc20TxParams)
 	var tokenDecimal = extraParams.TokenDecimal
 	var contractAddress = extraParams.ContractAddress
+	tokenChainId := TronMainnetTokenChainId
+	if testNet {
+		tokenChainId = TronNileTokenChainId
+	}
+	if err := checkRegisteredToken(big.NewInt(tokenChainId), contractAddress, tokenDecimal); err != nil {
+		return nil, err
+	}
 	transferContract, err := coin.CreateContract(extraParams.FromAddress, extraParams.ToAddress, extraParams.Amount, contractAddress, tokenDecimal)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,128 @@
+package coins
+
+import (
+	"fmt"
+	"math/big"
+	"wallet-sdk/src/errors"
+	types2 "wallet-sdk/src/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// DefaultFeeBumpPercent is the minimum fee increase nodes accept to replace a pending transaction
+const DefaultFeeBumpPercent = 10
+
+// ReplacementFees are the fees of a replacement transaction. Each fee is the higher of the bumped fee
+// of the pending transaction and the fee given here, so current network fees can be passed as they are.
+type ReplacementFees struct {
+	// BumpPercent is the minimum increase over the pending fees, DefaultFeeBumpPercent when 0 and never less
+	BumpPercent          int64         `json:"bumpPercent"`
+	GasPrice             types2.BigInt `json:"ethereumGasPrice"`
+	MaxFeePerGas         types2.BigInt `json:"ethereumMaxFeePerGas"`
+	MaxPriorityFeePerGas types2.BigInt `json:"ethereumMaxPriorityFeePerGas"`
+}
+
+// SpeedUpTransaction returns the pending transaction rawTx with bumped fees and the same nonce,
+// to sign with SignTx of the coin of its chain. The replacement keeps the chain id of rawTx,
+// signing it for another chain fails with ErrorChainIdMismatch.
+func SpeedUpTransaction(rawTx string, fees ReplacementFees) (*types2.BaseTransaction, error) {
+	tx, err := DecodeTx(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	return replacementTransaction(tx, tx.To(), tx.Value(), tx.Data(), tx.Gas(), tx.AccessList(), fees)
+}
+
+// CancelTransaction returns a zero value transfer of the sender to itself replacing the pending
+// transaction rawTx, which must be signed as the sender is recovered from it
+func CancelTransaction(rawTx string, fees ReplacementFees) (*types2.BaseTransaction, error) {
+	tx, err := DecodeTx(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	if !isSignedEvmTx(tx) {
+		return nil, errors.ErrorInvalidSignature
+	}
+	from, err := evmTxSender(tx)
+	if err != nil {
+		return nil, err
+	}
+	return replacementTransaction(tx, &from, big.NewInt(0), nil, params.TxGas, nil, fees)
+}
+
+// replacementTransaction builds a transaction of the type and chain of tx with its nonce and bumped fees.
+// An unsigned legacy transaction has no chain id, the one of a protected tx is kept in V as EIP-155 encodes it.
+func replacementTransaction(tx *types.Transaction, to *common.Address, value *big.Int, data []byte, gas uint64, accessList types.AccessList, fees ReplacementFees) (*types2.BaseTransaction, error) {
+	bumpPercent := fees.BumpPercent
+	if bumpPercent == 0 {
+		bumpPercent = DefaultFeeBumpPercent
+	}
+	if bumpPercent < DefaultFeeBumpPercent {
+		// nodes reject replacements raising the fees by less than 10%
+		return nil, fmt.Errorf("%w: bump of %d%%, at least %d%%", errors.ErrorInvalidFee, bumpPercent, DefaultFeeBumpPercent)
+	}
+
+	var replacement *types.Transaction
+	switch tx.Type() {
+	case types.LegacyTxType:
+		legacyTx := &types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: bumpFee(tx.GasPrice(), bumpPercent, &fees.GasPrice.Int),
+			Gas:      gas,
+			To:       to,
+			Value:    value,
+			Data:     data,
+		}
+		if tx.Protected() {
+			legacyTx.V = new(big.Int).Add(new(big.Int).Mul(tx.ChainId(), big.NewInt(2)), big.NewInt(35))
+		}
+		replacement = types.NewTx(legacyTx)
+	case types.AccessListTxType:
+		replacement = types.NewTx(&types.AccessListTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      tx.Nonce(),
+			GasPrice:   bumpFee(tx.GasPrice(), bumpPercent, &fees.GasPrice.Int),
+			Gas:        gas,
+			To:         to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		})
+	case types.DynamicFeeTxType:
+		tip := bumpFee(tx.GasTipCap(), bumpPercent, &fees.MaxPriorityFeePerGas.Int)
+		feeCap := bumpFee(tx.GasFeeCap(), bumpPercent, &fees.MaxFeePerGas.Int)
+		if feeCap.Cmp(tip) < 0 {
+			feeCap = tip
+		}
+		replacement = types.NewTx(&types.DynamicFeeTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      tx.Nonce(),
+			GasTipCap:  tip,
+			GasFeeCap:  feeCap,
+			Gas:        gas,
+			To:         to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		})
+	default:
+		return nil, errors.ErrorInvalidInput
+	}
+
+	transaction := types2.BaseTransaction{}
+	transaction.CoinTransaction = replacement
+	return &transaction, nil
+}
+
+// bumpFee returns fee raised by percent, rounded up, or minimum when it is higher
+func bumpFee(fee *big.Int, percent int64, minimum *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+percent))
+	bumped.Add(bumped, big.NewInt(99))
+	bumped.Div(bumped, big.NewInt(100))
+	if minimum != nil && minimum.Cmp(bumped) > 0 {
+		return new(big.Int).Set(minimum)
+	}
+	return bumped
+}
@@ -0,0 +1,21 @@
+package coins
+
+import (
+	"wallet-sdk/src/signer/ethereum_signer"
+	"wallet-sdk/src/types"
+)
+
+// SignTypedDataOnNetwork signs an eth_signTypedData_v4 request with an evm coin on network,
+// typed data whose domain names another chain is rejected
+func SignTypedDataOnNetwork(coin Coin, typedDataJson string, network Network, privateKey types.PrivateKey) (*ethereum_signer.TypedDataSignature, error) {
+	chainId, err := EvmChainId(coin, network)
+	if err != nil {
+		return nil, err
+	}
+	return ethereum_signer.SignTypedDataJson(typedDataJson, chainId, privateKey)
+}
+
+// SignTypedData signs an eth_signTypedData_v4 request with an evm coin, see SignTypedDataOnNetwork
+func SignTypedData(coin Coin, typedDataJson string, testNet bool, privateKey types.PrivateKey) (*ethereum_signer.TypedDataSignature, error) {
+	return SignTypedDataOnNetwork(coin, typedDataJson, NetworkFromTestNet(coin.GetCurrency(), testNet), privateKey)
+}
@@ -0,0 +1,437 @@
+package coins
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"wallet-sdk/src/errors"
+	"wallet-sdk/src/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/shopspring/decimal"
+)
+
+type UniswapVersion string
+
+const (
+	// UniswapV2 swaps through the swapExact* functions of Router02
+	UniswapV2 UniswapVersion = "v2"
+	// UniswapV3 swaps through exactInputSingle and exactInput of SwapRouter02
+	UniswapV3 UniswapVersion = "v3"
+)
+
+const (
+	// DefaultUniswapDeadline is how long a swap stays valid when no deadline is given, in seconds
+	DefaultUniswapDeadline = 20 * 60
+	// UniswapSwapGas and UniswapHopGas estimate the gas of a swap when GasLimit is 0
+	UniswapSwapGas    = 180000
+	UniswapHopGas     = 90000
+	UniswapApproveGas = 60000
+	UniswapWrapGas    = 50000
+)
+
+// UniswapRouters are the Uniswap contracts of a chain, WrappedNative is the WETH9 of the native coin
+type UniswapRouters struct {
+	V2Router      string `json:"v2Router"`
+	V3Router      string `json:"v3Router"`
+	WrappedNative string `json:"wrappedNative"`
+}
+
+const uniswapAbi = `[
+{"type":"function","name":"swapExactTokensForTokens","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+{"type":"function","name":"swapExactETHForTokens","stateMutability":"payable","inputs":[{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+{"type":"function","name":"swapExactTokensForETH","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+{"type":"function","name":"swapExactTokensForTokensSupportingFeeOnTransferTokens","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+{"type":"function","name":"swapExactETHForTokensSupportingFeeOnTransferTokens","stateMutability":"payable","inputs":[{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+{"type":"function","name":"swapExactTokensForETHSupportingFeeOnTransferTokens","inputs":[{"name":"amountIn","type":"uint256"},{"name":"amountOutMin","type":"uint256"},{"name":"path","type":"address[]"},{"name":"to","type":"address"},{"name":"deadline","type":"uint256"}]},
+{"type":"function","name":"exactInputSingle","stateMutability":"payable","inputs":[{"name":"params","type":"tuple","components":[{"name":"tokenIn","type":"address"},{"name":"tokenOut","type":"address"},{"name":"fee","type":"uint24"},{"name":"recipient","type":"address"},{"name":"amountIn","type":"uint256"},{"name":"amountOutMinimum","type":"uint256"},{"name":"sqrtPriceLimitX96","type":"uint160"}]}]},
+{"type":"function","name":"exactInput","stateMutability":"payable","inputs":[{"name":"params","type":"tuple","components":[{"name":"path","type":"bytes"},{"name":"recipient","type":"address"},{"name":"amountIn","type":"uint256"},{"name":"amountOutMinimum","type":"uint256"}]}]},
+{"type":"function","name":"unwrapWETH9","stateMutability":"payable","inputs":[{"name":"amountMinimum","type":"uint256"},{"name":"recipient","type":"address"}]},
+{"type":"function","name":"multicall","stateMutability":"payable","inputs":[{"name":"deadline","type":"uint256"},{"name":"data","type":"bytes[]"}]},
+{"type":"function","name":"deposit","stateMutability":"payable","inputs":[]},
+{"type":"function","name":"withdraw","inputs":[{"name":"wad","type":"uint256"}]},
+{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"amount","type":"uint256"}]}]`
+
+// swapRouter02AddressThis is the recipient that keeps the output in SwapRouter02, to unwrap it afterwards
+var swapRouter02AddressThis = common.BigToAddress(big.NewInt(2))
+
+var uniswapMethods abi.ABI
+
+// uniswapRouters are the routers of each chain id
+var uniswapRouters = make(map[string]UniswapRouters, 0)
+
+func init() {
+	parsed, err := abi.JSON(strings.NewReader(uniswapAbi))
+	if err != nil {
+		panic(err)
+	}
+	uniswapMethods = parsed
+	_ = RegisterEvmCallDecoder(uniswapAbi)
+
+	for chainId, routers := range map[int64]UniswapRouters{
+		MAINNET:    {V2Router: "0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D", V3Router: "0x68b3465833fb72A70ecDF485E0e4C7bD8665Fc45", WrappedNative: "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"},
+		TESTNET:    {V2Router: "0xeE567Fe1712Faf6149d80dA1E6934E354124CfE3", V3Router: "0x3bFA4769FB09eefC5a80d6E87c3B9C650f7Ae48E", WrappedNative: "0xfFf9976782d46CC05630D1f6eBAb18b2324d6B14"},
+		ARB1_MAIN:  {V2Router: "0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24", V3Router: "0x68b3465833fb72A70ecDF485E0e4C7bD8665Fc45", WrappedNative: "0x82aF49447D8a07e3bd95BD0d56f35241523fBab1"},
+		OPT_MAIN:   {V2Router: "0x4A7b5Da61326A6379179b40d00F57E5bbDC962c2", V3Router: "0x68b3465833fb72A70ecDF485E0e4C7bD8665Fc45", WrappedNative: "0x4200000000000000000000000000000000000006"},
+		MATIC_MAIN: {V2Router: "0xedf6066a2b290C185783862C7F4776A2C8077AD1", V3Router: "0x68b3465833fb72A70ecDF485E0e4C7bD8665Fc45", WrappedNative: "0x0d500B1d8E8eF31E21C99d1Db9A6444d3ADf1270"},
+		BNB_MAIN:   {V2Router: "0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24", V3Router: "0xB971eF87ede563556b2ED4b1C0b0019111Dd85d2", WrappedNative: "0xbb4CdB9CBd36B01bD1cBaEBF2De08d9173bc095c"},
+		AVAX_MAIN:  {V2Router: "0x4752ba5DBc23f44D87826276BF6Fd6b1C372aD24", V3Router: "0xbb00FF08d01D300023C629E8fFfFcb65A5a578cE", WrappedNative: "0xB31f66AA3C1e785363F0875A1B74E27b85FD66c7"},
+	} {
+		if err := RegisterUniswapRouters(big.NewInt(chainId), routers); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterUniswapRouters sets the Uniswap contracts of the chain chainId, a router may be empty
+// when the chain has no deployment of that version
+func RegisterUniswapRouters(chainId *big.Int, routers UniswapRouters) error {
+	if chainId == nil || chainId.Sign() <= 0 {
+		return errors.ErrorNetworkNotSupported
+	}
+	if err := validateUniswapRouters(routers, checksumChainId(chainId)); err != nil {
+		return err
+	}
+	uniswapRouters[chainId.String()] = routers
+	return nil
+}
+
+// validateUniswapRouters checks the contracts with the EIP-1191 checksum of checksumChainId when not nil
+func validateUniswapRouters(routers UniswapRouters, checksumChainId *big.Int) error {
+	if err := validateChecksumAddress(routers.WrappedNative, checksumChainId); err != nil {
+		return errors.ErrorInvalidContractAddress
+	}
+	for _, router := range []string{routers.V2Router, routers.V3Router} {
+		if router == "" {
+			continue
+		}
+		if err := validateChecksumAddress(router, checksumChainId); err != nil {
+			return errors.ErrorInvalidContractAddress
+		}
+	}
+	return nil
+}
+
+// GetUniswapRouters returns the Uniswap contracts of the chain chainId
+func GetUniswapRouters(chainId *big.Int) (UniswapRouters, error) {
+	if chainId == nil {
+		return UniswapRouters{}, errors.ErrorNetworkNotSupported
+	}
+	routers, ok := uniswapRouters[chainId.String()]
+	if !ok {
+		return UniswapRouters{}, errors.ErrorNetworkNotSupported
+	}
+	return routers, nil
+}
+
+// UniswapSwapParams swaps exactly AmountIn of TokenIn for at least AmountOutMin of TokenOut.
+// EthTxParams gives the nonce and fees, the swap takes the next nonce when an approval comes first.
+type UniswapSwapParams struct {
+	EthTxParams
+	Version UniswapVersion `json:"swapVersion"`
+	// TokenIn and TokenOut are empty for the native coin, wrapped or unwrapped on the way
+	TokenIn         string `json:"tokenIn"`
+	TokenInDecimal  int64  `json:"tokenInDecimal"`
+	TokenOut        string `json:"tokenOut"`
+	TokenOutDecimal int64  `json:"tokenOutDecimal"`
+	// Via are the tokens between TokenIn and TokenOut of a multi-hop swap
+	Via []string `json:"via"`
+	// Fees are the v3 pool fees of each hop in hundredths of a bip, like 3000 for 0.3%
+	Fees     []uint32        `json:"fees"`
+	AmountIn decimal.Decimal `json:"amountIn"`
+	// AmountOutMin is QuoteOut less SlippageBps when it is zero
+	AmountOutMin decimal.Decimal `json:"amountOutMin"`
+	QuoteOut     decimal.Decimal `json:"quoteOut"`
+	SlippageBps  int64           `json:"slippageBps"`
+	Recipient    string          `json:"recipient"`
+	// Deadline is the unix time the swap expires, DefaultUniswapDeadline from now when 0
+	Deadline int64 `json:"deadline"`
+	// Allowance is the allowance of the router, an approval of AmountIn comes first when it is lower
+	Allowance types.BigInt `json:"allowance"`
+	// FeeOnTransfer uses the v2 functions supporting tokens that take a fee on transfers
+	FeeOnTransfer bool `json:"feeOnTransfer"`
+}
+
+// UniswapSwap is a swap to create with the coin of the chain, after the approval when there is one
+type UniswapSwap struct {
+	Approval     *EthTxParams `json:"approval,omitempty"`
+	Swap         EthTxParams  `json:"swap"`
+	AmountIn     *big.Int     `json:"amountIn"`
+	AmountOutMin *big.Int     `json:"amountOutMin"`
+	Deadline     int64        `json:"deadline"`
+}
+
+// v3 SwapRouter02 parameter structs
+type exactInputSingleParams struct {
+	TokenIn           common.Address
+	TokenOut          common.Address
+	Fee               *big.Int
+	Recipient         common.Address
+	AmountIn          *big.Int
+	AmountOutMinimum  *big.Int
+	SqrtPriceLimitX96 *big.Int
+}
+
+type exactInputParams struct {
+	Path             []byte
+	Recipient        common.Address
+	AmountIn         *big.Int
+	AmountOutMinimum *big.Int
+}
+
+// BuildUniswapSwapOnNetwork builds a swap on the chain of an evm coin on network
+func BuildUniswapSwapOnNetwork(coin Coin, network Network, params UniswapSwapParams) (*UniswapSwap, error) {
+	chainId, err := EvmChainId(coin, network)
+	if err != nil {
+		return nil, err
+	}
+	return BuildUniswapSwap(params, chainId)
+}
+
+// BuildUniswapSwap builds a swap through the router of the chain chainId. Swapping the native coin
+// for its wrapped token, or back, calls the wrapped token directly.
+func BuildUniswapSwap(params UniswapSwapParams, chainId *big.Int) (*UniswapSwap, error) {
+	routers, err := GetUniswapRouters(chainId)
+	if err != nil {
+		return nil, err
+	}
+	if err = ValidateAddressOnChain(params.Recipient, chainId); err != nil {
+		return nil, err
+	}
+	nativeIn, nativeOut := params.TokenIn == "", params.TokenOut == ""
+	tokenIn, tokenOut := params.TokenIn, params.TokenOut
+	decimalsIn, decimalsOut := params.TokenInDecimal, params.TokenOutDecimal
+	if nativeIn {
+		tokenIn, decimalsIn = routers.WrappedNative, 18
+	}
+	if nativeOut {
+		tokenOut, decimalsOut = routers.WrappedNative, 18
+	}
+	if nativeIn && nativeOut {
+		return nil, fmt.Errorf("%w: the native coin can't be swapped for itself", errors.ErrorInvalidInput)
+	}
+	amountIn := ToWei(params.AmountIn, decimalsIn)
+	if amountIn.Sign() <= 0 {
+		return nil, errors.ErrorInvalidAmount
+	}
+	wrapped := common.HexToAddress(routers.WrappedNative)
+	if len(params.Via) == 0 && (nativeIn && isHexAddressOf(tokenOut, wrapped) || nativeOut && isHexAddressOf(tokenIn, wrapped)) {
+		return buildWrapSwap(params, routers.WrappedNative, nativeIn, amountIn)
+	}
+	path := append(append([]string{tokenIn}, params.Via...), tokenOut)
+	for i, token := range path {
+		if err = ValidateAddressOnChain(token, chainId); err != nil {
+			return nil, errors.ErrorInvalidContractAddress
+		}
+		if i > 0 && common.HexToAddress(token) == common.HexToAddress(path[i-1]) {
+			return nil, fmt.Errorf("%w: the swap path repeats %s", errors.ErrorInvalidInput, token)
+		}
+	}
+
+	amountOutMin := ToWei(params.AmountOutMin, decimalsOut)
+	if amountOutMin.Sign() == 0 {
+		if amountOutMin, err = UniswapAmountOutMin(ToWei(params.QuoteOut, decimalsOut), params.SlippageBps); err != nil {
+			return nil, err
+		}
+	}
+	if amountOutMin.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: a swap needs a minimum output or a quote", errors.ErrorInvalidAmount)
+	}
+	deadline := params.Deadline
+	if deadline == 0 {
+		deadline = time.Now().Unix() + DefaultUniswapDeadline
+	}
+	if deadline <= time.Now().Unix() {
+		return nil, fmt.Errorf("%w: the deadline has passed", errors.ErrorInvalidInput)
+	}
+
+	addresses := make([]common.Address, len(path))
+	for i, token := range path {
+		addresses[i] = common.HexToAddress(token)
+	}
+	recipient := common.HexToAddress(params.Recipient)
+	var router string
+	var data []byte
+	switch params.Version {
+	case UniswapV2:
+		if len(params.Fees) > 0 {
+			return nil, fmt.Errorf("%w: v2 pools have no fee tiers", errors.ErrorInvalidInput)
+		}
+		router = routers.V2Router
+		data, err = encodeUniswapV2Swap(nativeIn, nativeOut, params.FeeOnTransfer, addresses, recipient, amountIn, amountOutMin, deadline)
+	case UniswapV3:
+		if params.FeeOnTransfer {
+			return nil, fmt.Errorf("%w: v3 doesn't support fee on transfer tokens", errors.ErrorInvalidInput)
+		}
+		router = routers.V3Router
+		data, err = encodeUniswapV3Swap(nativeOut, path, params.Fees, recipient, amountIn, amountOutMin, deadline, chainId)
+	default:
+		return nil, fmt.Errorf("%w: unknown uniswap version %s", errors.ErrorInvalidInput, params.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if router == "" {
+		return nil, fmt.Errorf("%w: no uniswap %s router on chain %s", errors.ErrorNetworkNotSupported, params.Version, chainId)
+	}
+
+	swap := UniswapSwap{AmountIn: amountIn, AmountOutMin: amountOutMin, Deadline: deadline}
+	nonce := params.Nonce
+	if !nativeIn && params.Allowance.Cmp(amountIn) < 0 {
+		approveData, err := packAbiMethod(uniswapMethods.Methods["approve"], common.HexToAddress(router), amountIn)
+		if err != nil {
+			return nil, err
+		}
+		approval := params.EthTxParams
+		approval.ToAddress = tokenIn
+		approval.Amount = decimal.Zero
+		approval.Data = hexutil.Encode(approveData)
+		approval.GasLimit = types.BigInt{Int: *big.NewInt(UniswapApproveGas)}
+		swap.Approval = &approval
+		nonce++
+	}
+
+	swap.Swap = params.EthTxParams
+	swap.Swap.Nonce = nonce
+	swap.Swap.ToAddress = router
+	swap.Swap.Amount = decimal.Zero
+	if nativeIn {
+		swap.Swap.Amount = decimal.NewFromBigInt(amountIn, -18)
+	}
+	swap.Swap.Data = hexutil.Encode(data)
+	if swap.Swap.GasLimit.Sign() == 0 {
+		swap.Swap.GasLimit = types.BigInt{Int: *big.NewInt(int64(UniswapSwapGas + UniswapHopGas*(len(path)-2)))}
+	}
+	return &swap, nil
+}
+
+// UniswapAmountOutMin is the minimum output of a quote less a slippage in basis points, rounded down
+func UniswapAmountOutMin(quote *big.Int, slippageBps int64) (*big.Int, error) {
+	if quote == nil || quote.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: a swap needs a minimum output or a quote", errors.ErrorInvalidAmount)
+	}
+	if slippageBps < 0 || slippageBps >= 10000 {
+		return nil, fmt.Errorf("%w: slippage of %d bps", errors.ErrorInvalidInput, slippageBps)
+	}
+	amountOutMin := new(big.Int).Mul(quote, big.NewInt(10000-slippageBps))
+	return amountOutMin.Div(amountOutMin, big.NewInt(10000)), nil
+}
+
+// EncodeUniswapV3Path encodes the path of exactInput on the chain chainId, each token followed by the fee of the pool to the next one
+func EncodeUniswapV3Path(tokens []string, fees []uint32, chainId *big.Int) ([]byte, error) {
+	if len(tokens) < 2 || len(fees) != len(tokens)-1 {
+		return nil, fmt.Errorf("%w: %d tokens need %d pool fees", errors.ErrorInvalidInput, len(tokens), len(tokens)-1)
+	}
+	var path []byte
+	for i, token := range tokens {
+		if err := ValidateAddressOnChain(token, chainId); err != nil {
+			return nil, errors.ErrorInvalidContractAddress
+		}
+		path = append(path, common.HexToAddress(token).Bytes()...)
+		if i == len(fees) {
+			break
+		}
+		if fees[i] == 0 || fees[i] >= 1<<24 {
+			return nil, fmt.Errorf("%w: pool fee %d", errors.ErrorInvalidInput, fees[i])
+		}
+		path = append(path, byte(fees[i]>>16), byte(fees[i]>>8), byte(fees[i]))
+	}
+	return path, nil
+}
+
+func encodeUniswapV2Swap(nativeIn bool, nativeOut bool, feeOnTransfer bool, path []common.Address, recipient common.Address,
+	amountIn *big.Int, amountOutMin *big.Int, deadline int64) ([]byte, error) {
+	method := "swapExactTokensForTokens"
+	switch {
+	case nativeIn:
+		method = "swapExactETHForTokens"
+	case nativeOut:
+		method = "swapExactTokensForETH"
+	}
+	if feeOnTransfer {
+		method += "SupportingFeeOnTransferTokens"
+	}
+	if nativeIn {
+		// the value of the transaction is the input
+		return packAbiMethod(uniswapMethods.Methods[method], amountOutMin, path, recipient, big.NewInt(deadline))
+	}
+	return packAbiMethod(uniswapMethods.Methods[method], amountIn, amountOutMin, path, recipient, big.NewInt(deadline))
+}
+
+// encodeUniswapV3Swap encodes a multicall checking the deadline, the router wraps the value of the
+// transaction itself and the output is unwrapped by a second call
+func encodeUniswapV3Swap(nativeOut bool, path []string, fees []uint32, recipient common.Address,
+	amountIn *big.Int, amountOutMin *big.Int, deadline int64, chainId *big.Int) ([]byte, error) {
+	encodedPath, err := EncodeUniswapV3Path(path, fees, chainId)
+	if err != nil {
+		return nil, err
+	}
+	swapRecipient := recipient
+	if nativeOut {
+		swapRecipient = swapRouter02AddressThis
+	}
+	var swapData []byte
+	if len(path) == 2 {
+		swapData, err = packAbiMethod(uniswapMethods.Methods["exactInputSingle"], exactInputSingleParams{
+			TokenIn:           common.HexToAddress(path[0]),
+			TokenOut:          common.HexToAddress(path[1]),
+			Fee:               big.NewInt(int64(fees[0])),
+			Recipient:         swapRecipient,
+			AmountIn:          amountIn,
+			AmountOutMinimum:  amountOutMin,
+			SqrtPriceLimitX96: new(big.Int),
+		})
+	} else {
+		swapData, err = packAbiMethod(uniswapMethods.Methods["exactInput"], exactInputParams{
+			Path:             encodedPath,
+			Recipient:        swapRecipient,
+			AmountIn:         amountIn,
+			AmountOutMinimum: amountOutMin,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	calls := [][]byte{swapData}
+	if nativeOut {
+		unwrapData, err := packAbiMethod(uniswapMethods.Methods["unwrapWETH9"], amountOutMin, recipient)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, unwrapData)
+	}
+	return packAbiMethod(uniswapMethods.Methods["multicall"], big.NewInt(deadline), calls)
+}
+
+func isHexAddressOf(address string, of common.Address) bool {
+	return common.IsHexAddress(address) && common.HexToAddress(address) == of
+}
+
+// buildWrapSwap wraps the native coin with deposit or unwraps it with withdraw, one for one.
+// The wrapped token credits the sender, whatever the recipient.
+func buildWrapSwap(params UniswapSwapParams, wrappedNative string, wrap bool, amount *big.Int) (*UniswapSwap, error) {
+	var data []byte
+	var err error
+	if wrap {
+		data, err = packAbiMethod(uniswapMethods.Methods["deposit"])
+	} else {
+		data, err = packAbiMethod(uniswapMethods.Methods["withdraw"], amount)
+	}
+	if err != nil {
+		return nil, err
+	}
+	txParams := params.EthTxParams
+	txParams.ToAddress = wrappedNative
+	txParams.Amount = decimal.Zero
+	if wrap {
+		txParams.Amount = decimal.NewFromBigInt(amount, -18)
+	}
+	txParams.Data = hexutil.Encode(data)
+	if txParams.GasLimit.Sign() == 0 {
+		txParams.GasLimit = types.BigInt{Int: *big.NewInt(UniswapWrapGas)}
+	}
+	return &UniswapSwap{Swap: txParams, AmountIn: amount, AmountOutMin: amount}, nil
+}
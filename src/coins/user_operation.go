@@ -0,0 +1,374 @@
+package coins
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"wallet-sdk/src/errors"
+	"wallet-sdk/src/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// canonical ERC-4337 deployments, the same on every chain
+const (
+	EntryPointV06Address           = "0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789"
+	EntryPointV07Address           = "0x0000000071727De22E5E9d8BAf0edAc6f37da032"
+	SimpleAccountFactoryV06Address = "0x9406Cc6185a346906296840746125a0E44976454"
+	SimpleAccountFactoryV07Address = "0x91E60e0613810449d098b0b5Ec8b51A0FE8c8985"
+)
+
+const userOperationAbi = `[
+{"type":"function","name":"handleOps","inputs":[{"name":"ops","type":"tuple[]","components":[{"name":"sender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"initCode","type":"bytes"},{"name":"callData","type":"bytes"},{"name":"callGasLimit","type":"uint256"},{"name":"verificationGasLimit","type":"uint256"},{"name":"preVerificationGas","type":"uint256"},{"name":"maxFeePerGas","type":"uint256"},{"name":"maxPriorityFeePerGas","type":"uint256"},{"name":"paymasterAndData","type":"bytes"},{"name":"signature","type":"bytes"}]},{"name":"beneficiary","type":"address"}]},
+{"type":"function","name":"handleOps","inputs":[{"name":"ops","type":"tuple[]","components":[{"name":"sender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"initCode","type":"bytes"},{"name":"callData","type":"bytes"},{"name":"accountGasLimits","type":"bytes32"},{"name":"preVerificationGas","type":"uint256"},{"name":"gasFees","type":"bytes32"},{"name":"paymasterAndData","type":"bytes"},{"name":"signature","type":"bytes"}]},{"name":"beneficiary","type":"address"}]},
+{"type":"function","name":"createAccount","inputs":[{"name":"owner","type":"address"},{"name":"salt","type":"uint256"}]},
+{"type":"function","name":"execute","inputs":[{"name":"dest","type":"address"},{"name":"value","type":"uint256"},{"name":"func","type":"bytes"}]},
+{"type":"function","name":"executeBatch","inputs":[{"name":"dest","type":"address[]"},{"name":"func","type":"bytes[]"}]},
+{"type":"function","name":"executeBatch","inputs":[{"name":"dest","type":"address[]"},{"name":"value","type":"uint256[]"},{"name":"func","type":"bytes[]"}]}]`
+
+var userOperationMethods abi.ABI
+
+func init() {
+	parsed, err := abi.JSON(strings.NewReader(userOperationAbi))
+	if err != nil {
+		panic(err)
+	}
+	userOperationMethods = parsed
+	_ = RegisterEvmCallDecoder(userOperationAbi)
+}
+
+// UserOperation is an ERC-4337 v0.6 user operation, it marshals to the JSON of eth_sendUserOperation
+type UserOperation struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                hexutil.Big    `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         hexutil.Big    `json:"callGasLimit"`
+	VerificationGasLimit hexutil.Big    `json:"verificationGasLimit"`
+	PreVerificationGas   hexutil.Big    `json:"preVerificationGas"`
+	MaxFeePerGas         hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas hexutil.Big    `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+// UserOperationV07 is an ERC-4337 v0.7 user operation in the unpacked form bundlers take,
+// Pack returns the form the EntryPoint hashes and executes
+type UserOperationV07 struct {
+	Sender                        common.Address  `json:"sender"`
+	Nonce                         hexutil.Big     `json:"nonce"`
+	Factory                       *common.Address `json:"factory,omitempty"`
+	FactoryData                   hexutil.Bytes   `json:"factoryData,omitempty"`
+	CallData                      hexutil.Bytes   `json:"callData"`
+	CallGasLimit                  hexutil.Big     `json:"callGasLimit"`
+	VerificationGasLimit          hexutil.Big     `json:"verificationGasLimit"`
+	PreVerificationGas            hexutil.Big     `json:"preVerificationGas"`
+	MaxFeePerGas                  hexutil.Big     `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas          hexutil.Big     `json:"maxPriorityFeePerGas"`
+	Paymaster                     *common.Address `json:"paymaster,omitempty"`
+	PaymasterVerificationGasLimit *hexutil.Big    `json:"paymasterVerificationGasLimit,omitempty"`
+	PaymasterPostOpGasLimit       *hexutil.Big    `json:"paymasterPostOpGasLimit,omitempty"`
+	PaymasterData                 hexutil.Bytes   `json:"paymasterData,omitempty"`
+	Signature                     hexutil.Bytes   `json:"signature"`
+}
+
+// PackedUserOperation is the v0.7 user operation struct of the EntryPoint
+type PackedUserOperation struct {
+	Sender             common.Address
+	Nonce              *big.Int
+	InitCode           []byte
+	CallData           []byte
+	AccountGasLimits   [32]byte
+	PreVerificationGas *big.Int
+	GasFees            [32]byte
+	PaymasterAndData   []byte
+	Signature          []byte
+}
+
+// AccountCall is one call made by a smart account
+type AccountCall struct {
+	To    string   `json:"to"`
+	Value *big.Int `json:"value"`
+	Data  []byte   `json:"data"`
+}
+
+// userOperationTuple is the v0.6 user operation struct of the EntryPoint
+type userOperationTuple struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+func (op UserOperation) tuple() userOperationTuple {
+	return userOperationTuple{
+		Sender:               op.Sender,
+		Nonce:                op.Nonce.ToInt(),
+		InitCode:             op.InitCode,
+		CallData:             op.CallData,
+		CallGasLimit:         op.CallGasLimit.ToInt(),
+		VerificationGasLimit: op.VerificationGasLimit.ToInt(),
+		PreVerificationGas:   op.PreVerificationGas.ToInt(),
+		MaxFeePerGas:         op.MaxFeePerGas.ToInt(),
+		MaxPriorityFeePerGas: op.MaxPriorityFeePerGas.ToInt(),
+		PaymasterAndData:     op.PaymasterAndData,
+		Signature:            op.Signature,
+	}
+}
+
+// Hash returns getUserOpHash of the EntryPoint at entryPoint on the chain chainId
+func (op UserOperation) Hash(entryPoint string, chainId *big.Int) (common.Hash, error) {
+	packed, err := abiEncode([]string{"address", "uint256", "bytes32", "bytes32", "uint256", "uint256", "uint256", "uint256", "uint256", "bytes32"},
+		op.Sender, op.Nonce.ToInt(), crypto.Keccak256Hash(op.InitCode), crypto.Keccak256Hash(op.CallData),
+		op.CallGasLimit.ToInt(), op.VerificationGasLimit.ToInt(), op.PreVerificationGas.ToInt(),
+		op.MaxFeePerGas.ToInt(), op.MaxPriorityFeePerGas.ToInt(), crypto.Keccak256Hash(op.PaymasterAndData))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return userOperationHash(packed, entryPoint, chainId)
+}
+
+// Sign sets the signature of a simple account owned by privateKey, see SignUserOperationHash
+func (op *UserOperation) Sign(entryPoint string, chainId *big.Int, privateKey types.PrivateKey) error {
+	hash, err := op.Hash(entryPoint, chainId)
+	if err != nil {
+		return err
+	}
+	op.Signature, err = SignUserOperationHash(hash, privateKey)
+	return err
+}
+
+// Pack packs the gas limits, fees, factory and paymaster fields the way the v0.7 EntryPoint takes them
+func (op UserOperationV07) Pack() (PackedUserOperation, error) {
+	packed := PackedUserOperation{
+		Sender:             op.Sender,
+		Nonce:              op.Nonce.ToInt(),
+		CallData:           op.CallData,
+		PreVerificationGas: op.PreVerificationGas.ToInt(),
+		Signature:          op.Signature,
+	}
+	var err error
+	if packed.AccountGasLimits, err = packUint128Pair(op.VerificationGasLimit.ToInt(), op.CallGasLimit.ToInt()); err != nil {
+		return PackedUserOperation{}, err
+	}
+	if packed.GasFees, err = packUint128Pair(op.MaxPriorityFeePerGas.ToInt(), op.MaxFeePerGas.ToInt()); err != nil {
+		return PackedUserOperation{}, err
+	}
+	if op.Factory != nil {
+		packed.InitCode = append(op.Factory.Bytes(), op.FactoryData...)
+	} else if len(op.FactoryData) > 0 {
+		return PackedUserOperation{}, fmt.Errorf("%w: factoryData without factory", errors.ErrorInvalidInput)
+	}
+	if op.Paymaster != nil {
+		gasLimits, err := packUint128Pair((*big.Int)(op.PaymasterVerificationGasLimit), (*big.Int)(op.PaymasterPostOpGasLimit))
+		if err != nil {
+			return PackedUserOperation{}, err
+		}
+		packed.PaymasterAndData = append(append(op.Paymaster.Bytes(), gasLimits[:]...), op.PaymasterData...)
+	} else if len(op.PaymasterData) > 0 {
+		return PackedUserOperation{}, fmt.Errorf("%w: paymasterData without paymaster", errors.ErrorInvalidInput)
+	}
+	return packed, nil
+}
+
+// Hash returns getUserOpHash of the v0.7 EntryPoint at entryPoint on the chain chainId
+func (op UserOperationV07) Hash(entryPoint string, chainId *big.Int) (common.Hash, error) {
+	packed, err := op.Pack()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return packed.Hash(entryPoint, chainId)
+}
+
+// Sign sets the signature of a simple account owned by privateKey, see SignUserOperationHash
+func (op *UserOperationV07) Sign(entryPoint string, chainId *big.Int, privateKey types.PrivateKey) error {
+	hash, err := op.Hash(entryPoint, chainId)
+	if err != nil {
+		return err
+	}
+	op.Signature, err = SignUserOperationHash(hash, privateKey)
+	return err
+}
+
+// Hash returns getUserOpHash of the v0.7 EntryPoint at entryPoint on the chain chainId
+func (op PackedUserOperation) Hash(entryPoint string, chainId *big.Int) (common.Hash, error) {
+	packed, err := abiEncode([]string{"address", "uint256", "bytes32", "bytes32", "bytes32", "uint256", "bytes32", "bytes32"},
+		op.Sender, op.Nonce, crypto.Keccak256Hash(op.InitCode), crypto.Keccak256Hash(op.CallData),
+		op.AccountGasLimits, op.PreVerificationGas, op.GasFees, crypto.Keccak256Hash(op.PaymasterAndData))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return userOperationHash(packed, entryPoint, chainId)
+}
+
+// SignUserOperationHash signs a user operation hash the way SimpleAccount checks it,
+// as an eth_sign message of the 32 hash bytes, v is 27 or 28
+func SignUserOperationHash(hash common.Hash, privateKey types.PrivateKey) ([]byte, error) {
+	key, err := crypto.ToECDSA(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	digest := crypto.Keccak256([]byte("\x19Ethereum Signed Message:\n32"), hash.Bytes())
+	signature, err := crypto.Sign(digest, key)
+	if err != nil {
+		return nil, err
+	}
+	signature[64] += 27
+	return signature, nil
+}
+
+// EncodeHandleOps encodes the handleOps call of the v0.6 EntryPoint on the chain chainId, fees are paid to beneficiary
+func EncodeHandleOps(ops []UserOperation, beneficiary string, chainId *big.Int) ([]byte, error) {
+	if err := ValidateAddressOnChain(beneficiary, chainId); err != nil {
+		return nil, err
+	}
+	tuples := make([]userOperationTuple, len(ops))
+	for i, op := range ops {
+		tuples[i] = op.tuple()
+	}
+	return packAbiMethod(userOperationMethods.Methods["handleOps"], tuples, common.HexToAddress(beneficiary))
+}
+
+// EncodeHandleOpsV07 encodes the handleOps call of the v0.7 EntryPoint on the chain chainId, fees are paid to beneficiary
+func EncodeHandleOpsV07(ops []UserOperationV07, beneficiary string, chainId *big.Int) ([]byte, error) {
+	if err := ValidateAddressOnChain(beneficiary, chainId); err != nil {
+		return nil, err
+	}
+	packed := make([]PackedUserOperation, len(ops))
+	for i, op := range ops {
+		var err error
+		if packed[i], err = op.Pack(); err != nil {
+			return nil, err
+		}
+	}
+	return packAbiMethod(userOperationMethods.Methods["handleOps0"], packed, common.HexToAddress(beneficiary))
+}
+
+// SimpleAccountFactoryData encodes createAccount(owner, salt) of the SimpleAccount factories,
+// it is the factoryData of a v0.7 user operation on the chain chainId
+func SimpleAccountFactoryData(owner string, salt *big.Int, chainId *big.Int) ([]byte, error) {
+	if err := ValidateAddressOnChain(owner, chainId); err != nil {
+		return nil, err
+	}
+	if salt == nil {
+		salt = big.NewInt(0)
+	}
+	return packAbiMethod(userOperationMethods.Methods["createAccount"], common.HexToAddress(owner), salt)
+}
+
+// SimpleAccountInitCode returns the initCode of a v0.6 user operation on the chain chainId deploying a SimpleAccount with factory
+func SimpleAccountInitCode(factory string, owner string, salt *big.Int, chainId *big.Int) ([]byte, error) {
+	if err := ValidateAddressOnChain(factory, chainId); err != nil {
+		return nil, err
+	}
+	factoryData, err := SimpleAccountFactoryData(owner, salt, chainId)
+	if err != nil {
+		return nil, err
+	}
+	return append(common.HexToAddress(factory).Bytes(), factoryData...), nil
+}
+
+// SimpleAccountExecuteData encodes the callData of a user operation on the chain chainId making one call from a SimpleAccount
+func SimpleAccountExecuteData(call AccountCall, chainId *big.Int) ([]byte, error) {
+	if err := ValidateAddressOnChain(call.To, chainId); err != nil {
+		return nil, err
+	}
+	value := call.Value
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	return packAbiMethod(userOperationMethods.Methods["execute"], common.HexToAddress(call.To), value, call.Data)
+}
+
+// SimpleAccountExecuteBatchData encodes the callData of a user operation on the chain chainId making several calls
+// from a SimpleAccount. The v0.6 SimpleAccount can't send value in a batch.
+func SimpleAccountExecuteBatchData(entryPoint string, calls []AccountCall, chainId *big.Int) ([]byte, error) {
+	var to []common.Address
+	var values []*big.Int
+	var data [][]byte
+	hasValue := false
+	for _, call := range calls {
+		if err := ValidateAddressOnChain(call.To, chainId); err != nil {
+			return nil, err
+		}
+		value := call.Value
+		if value == nil {
+			value = big.NewInt(0)
+		}
+		hasValue = hasValue || value.Sign() != 0
+		to = append(to, common.HexToAddress(call.To))
+		values = append(values, value)
+		data = append(data, call.Data)
+	}
+	switch common.HexToAddress(entryPoint) {
+	case common.HexToAddress(EntryPointV06Address):
+		if hasValue {
+			return nil, fmt.Errorf("%w: the v0.6 SimpleAccount doesn't send value in executeBatch", errors.ErrorInvalidAmount)
+		}
+		return packAbiMethod(userOperationMethods.Methods["executeBatch"], to, data)
+	case common.HexToAddress(EntryPointV07Address):
+		return packAbiMethod(userOperationMethods.Methods["executeBatch0"], to, values, data)
+	}
+	return nil, fmt.Errorf("%w: unknown entry point %s", errors.ErrorInvalidContractAddress, entryPoint)
+}
+
+// userOperationHash hashes the packed user operation with the entry point and the chain id
+func userOperationHash(packed []byte, entryPoint string, chainId *big.Int) (common.Hash, error) {
+	if err := ValidateAddressOnChain(entryPoint, chainId); err != nil {
+		return common.Hash{}, err
+	}
+	if chainId == nil || chainId.Sign() <= 0 {
+		return common.Hash{}, errors.ErrorNetworkNotSupported
+	}
+	encoded, err := abiEncode([]string{"bytes32", "address", "uint256"}, crypto.Keccak256Hash(packed), common.HexToAddress(entryPoint), chainId)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// packUint128Pair packs high and low into one bytes32, nil is 0
+func packUint128Pair(high *big.Int, low *big.Int) ([32]byte, error) {
+	var packed [32]byte
+	for i, n := range []*big.Int{high, low} {
+		if n == nil {
+			continue
+		}
+		if n.Sign() < 0 || n.BitLen() > 128 {
+			return packed, fmt.Errorf("%w: %s doesn't fit in uint128", errors.ErrorInvalidInput, n)
+		}
+		n.FillBytes(packed[i*16 : (i+1)*16])
+	}
+	return packed, nil
+}
+
+// abiEncode is solidity abi.encode of values of the given types
+func abiEncode(typeNames []string, values ...interface{}) ([]byte, error) {
+	var arguments abi.Arguments
+	for _, typeName := range typeNames {
+		t, err := abi.NewType(typeName, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, abi.Argument{Type: t})
+	}
+	return arguments.Pack(values...)
+}
+
+func packAbiMethod(method abi.Method, args ...interface{}) ([]byte, error) {
+	packed, err := method.Inputs.Pack(args...)
+	if err != nil {
+		return nil, err
+	}
+	return append(method.ID, packed...), nil
+}
@@ -0,0 +1,380 @@
+package coins
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"wallet-sdk/src/errors"
+	"wallet-sdk/src/types"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcwallet/wallet/txauthor"
+)
+
+// sighash variants of UtxoChainConfig
+const (
+	// UtxoSigHashDefault signs like bitcoin, legacy sighash for p2pkh and BIP143 for segwit inputs
+	UtxoSigHashDefault = ""
+	// UtxoSigHashForkID signs every input with BIP143 and SIGHASH_FORKID, as Bitcoin Gold does
+	UtxoSigHashForkID = "forkid"
+
+	sigHashForkID txscript.SigHashType = 0x40
+)
+
+// UtxoNetConfig holds the address and key prefixes of one network of a bitcoin fork
+type UtxoNetConfig struct {
+	Name             string `json:"name"`
+	PubKeyHashAddrID byte   `json:"pubKeyHashAddrId"`
+	ScriptHashAddrID byte   `json:"scriptHashAddrId"`
+	// PrivateKeyID is the WIF prefix
+	PrivateKeyID    byte   `json:"privateKeyId"`
+	Bech32HRPSegwit string `json:"bech32Hrp"`
+	// Net is the network magic, needed with a bech32 hrp to register the net for segwit address decoding
+	Net uint32 `json:"net"`
+	// CoinType is the SLIP-44 coin type used in the bip44 path
+	CoinType uint32 `json:"coinType"`
+}
+
+// UtxoChainConfig describes a bitcoin fork served by the Btc engine.
+// TestNet is optional, chains without one use MainNet for both.
+type UtxoChainConfig struct {
+	Currency string `json:"currency"`
+	// Decimals can only be 8, the default, the Btc engine encodes amounts in satoshis
+	Decimals int `json:"decimals"`
+	// DustLimit is in satoshis, change below it is left to the fee
+	DustLimit int64          `json:"dustLimit"`
+	SigHash   string         `json:"sigHash"`
+	ForkID    uint32         `json:"forkId"`
+	MainNet   UtxoNetConfig  `json:"mainNet"`
+	TestNet   *UtxoNetConfig `json:"testNet,omitempty"`
+}
+
+// utxoNets are the network magics registered in chaincfg, which knows the bitcoin nets from the start
+var utxoNets = map[wire.BitcoinNet]bool{
+	chaincfg.MainNetParams.Net:       true,
+	chaincfg.TestNet3Params.Net:      true,
+	chaincfg.RegressionNetParams.Net: true,
+	chaincfg.SimNetParams.Net:        true,
+	chaincfg.SigNetParams.Net:        true,
+}
+
+type UtxoChain struct {
+	Btc
+	config UtxoChainConfig
+}
+
+// RegisterUtxoChain registers a bitcoin fork described by config as a utxo coin
+func RegisterUtxoChain(config UtxoChainConfig) (Coin, error) {
+	coin, err := NewUtxoChain(config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := GetCoin(coin.GetCurrency()); err == nil {
+		return nil, errors.ErrorCurrencyAlreadyRegistered
+	}
+	if err := coin.register(); err != nil {
+		return nil, err
+	}
+	return coin, nil
+}
+
+// RegisterUtxoChainsFromJson registers every chain of a JSON array of UtxoChainConfig.
+// Nothing is registered when one of the configs is invalid.
+func RegisterUtxoChainsFromJson(configJson []byte) ([]Coin, error) {
+	var configs []UtxoChainConfig
+	if err := json.Unmarshal(configJson, &configs); err != nil {
+		return nil, err
+	}
+	var chains []UtxoChain
+	seen := make(map[string]bool, len(configs))
+	seenHrps := make(map[string]bool, len(configs))
+	seenMagics := make(map[uint32]bool, len(configs))
+	for _, config := range configs {
+		coin, err := NewUtxoChain(config)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := GetCoin(coin.GetCurrency()); err == nil || seen[coin.GetCurrency()] {
+			return nil, errors.ErrorCurrencyAlreadyRegistered
+		}
+		seen[coin.GetCurrency()] = true
+		for _, net := range coin.segwitNets() {
+			hrp := strings.ToLower(net.Bech32HRPSegwit)
+			if seenHrps[hrp] || seenMagics[net.Net] {
+				return nil, fmt.Errorf("%w: %s", errors.ErrorNetworkAlreadyRegistered, net.Name)
+			}
+			seenHrps[hrp] = true
+			seenMagics[net.Net] = true
+		}
+		chains = append(chains, coin)
+	}
+
+	var coins []Coin
+	for _, coin := range chains {
+		if err := coin.register(); err != nil {
+			return nil, err
+		}
+		coins = append(coins, coin)
+	}
+	return coins, nil
+}
+
+// RegisterUtxoChainsFromFile registers the chains of a JSON file, see RegisterUtxoChainsFromJson
+func RegisterUtxoChainsFromFile(path string) ([]Coin, error) {
+	configJson, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return RegisterUtxoChainsFromJson(configJson)
+}
+
+// NewUtxoChain validates config and fills in its defaults, without registering the coin
+func NewUtxoChain(config UtxoChainConfig) (UtxoChain, error) {
+	config.Currency = strings.ToUpper(config.Currency)
+	if config.Currency == "" || config.MainNet.Name == "" {
+		return UtxoChain{}, errors.ErrorInvalidInput
+	}
+	if config.TestNet != nil && config.TestNet.Name == "" {
+		return UtxoChain{}, errors.ErrorInvalidInput
+	}
+	if config.SigHash != UtxoSigHashDefault && config.SigHash != UtxoSigHashForkID {
+		return UtxoChain{}, fmt.Errorf("unknown sighash variant %s", config.SigHash)
+	}
+	if config.Decimals == 0 {
+		config.Decimals = 8
+	}
+	if config.Decimals != 8 {
+		// amounts are encoded by the Btc engine, always in units of 1e-8
+		return UtxoChain{}, fmt.Errorf("%w: %d decimals, utxo chains have 8", errors.ErrorInvalidInput, config.Decimals)
+	}
+	if config.DustLimit == 0 {
+		config.DustLimit = MinNondustOutput
+	}
+	coin := UtxoChain{config: config}
+	nets := coin.segwitNets()
+	if len(nets) == 2 && (nets[0].Net == nets[1].Net || strings.EqualFold(nets[0].Bech32HRPSegwit, nets[1].Bech32HRPSegwit)) {
+		return UtxoChain{}, fmt.Errorf("%w: %s", errors.ErrorNetworkAlreadyRegistered, nets[1].Name)
+	}
+	for _, net := range nets {
+		if net.Net == 0 {
+			return UtxoChain{}, fmt.Errorf("%w: %s has a bech32 hrp but no network magic", errors.ErrorInvalidInput, net.Name)
+		}
+		if utxoNets[wire.BitcoinNet(net.Net)] || chaincfg.IsBech32SegwitPrefix(net.Bech32HRPSegwit+"1") {
+			return UtxoChain{}, fmt.Errorf("%w: %s", errors.ErrorNetworkAlreadyRegistered, net.Name)
+		}
+	}
+	return coin, nil
+}
+
+// segwitNets are the net configs with a bech32 hrp, which register their params in chaincfg
+func (coin UtxoChain) segwitNets() []UtxoNetConfig {
+	var nets []UtxoNetConfig
+	if coin.config.MainNet.Bech32HRPSegwit != "" {
+		nets = append(nets, coin.config.MainNet)
+	}
+	if coin.config.TestNet != nil && coin.config.TestNet.Bech32HRPSegwit != "" {
+		nets = append(nets, *coin.config.TestNet)
+	}
+	return nets
+}
+
+// register adds the coin and its networks, the network ids are the configured net names.
+// Nets with a bech32 hrp are registered in chaincfg too, btcutil.DecodeAddress only decodes known segwit hrps.
+func (coin UtxoChain) register() error {
+	for _, testNet := range []bool{false, true} {
+		if testNet && coin.config.TestNet == nil || coin.netConfig(testNet).Bech32HRPSegwit == "" {
+			continue
+		}
+		params := coin.GetNetParams(testNet)
+		if err := chaincfg.Register(&params); err != nil {
+			if err == chaincfg.ErrDuplicateNet {
+				return fmt.Errorf("%w: %s", errors.ErrorNetworkAlreadyRegistered, params.Name)
+			}
+			return err
+		}
+		utxoNets[params.Net] = true
+	}
+	RegisterUtxoCoin(coin)
+	mainNetParams := coin.GetNetParams(false)
+	RegisterNetwork(coin.GetCurrency(), Network{ID: coin.config.MainNet.Name, Kind: NetworkMainNet, ChainParams: &mainNetParams})
+	if coin.config.TestNet != nil {
+		testNetParams := coin.GetNetParams(true)
+		RegisterNetwork(coin.GetCurrency(), Network{ID: coin.config.TestNet.Name, Kind: NetworkTestNet, ChainParams: &testNetParams})
+	}
+	return nil
+}
+
+func (coin UtxoChain) GetCurrency() string {
+	return coin.config.Currency
+}
+
+func (coin UtxoChain) ChainName() string {
+	return coin.config.Currency
+}
+
+func (coin UtxoChain) GetDecimal() int {
+	return coin.config.Decimals
+}
+
+func (coin UtxoChain) GetPath(index int64, testNet bool) string {
+	return fmt.Sprintf(coin.GetBasePath(testNet), 0, 0, index)
+}
+
+func (coin UtxoChain) GetBasePath(testNet bool) string {
+	return fmt.Sprintf("m/44'/%d'/%%d'/%%d/%%d", coin.netConfig(testNet).CoinType)
+}
+
+func (coin UtxoChain) PrivateKeyToString(key types.PrivateKey) (string, error) {
+	netParams := coin.GetNetParams(false)
+	btcPrivKey, _ := btcec.PrivKeyFromBytes(key)
+	wif, err := btcutil.NewWIF(btcPrivKey, &netParams, true)
+	if err != nil {
+		return "", err
+	}
+	return wif.String(), nil
+}
+
+func (coin UtxoChain) GenerateAddress(privateKey types.PrivateKey, testNet bool) (*types.CoinAddress, error) {
+	netParams := coin.GetNetParams(testNet)
+	return coin.GenAddress(privateKey, netParams)
+}
+
+func (coin UtxoChain) CreateTransaction(txParams types.TxParams, testNet bool) (*types.BaseTransaction, error) {
+	netParams := coin.GetNetParams(testNet)
+	return coin.transaction(txParams, netParams, btcutil.Amount(coin.config.DustLimit))
+}
+
+func (coin UtxoChain) CreateTransactionOnNetwork(txParams types.TxParams, network Network) (*types.BaseTransaction, error) {
+	netParams, err := networkChainParams(network)
+	if err != nil {
+		return nil, err
+	}
+	return coin.transaction(txParams, netParams, btcutil.Amount(coin.config.DustLimit))
+}
+
+func (coin UtxoChain) SignTx(baseTransaction *types.BaseTransaction, testNet bool, privateKey types.PrivateKey) (*string, error) {
+	return coin.signTx(baseTransaction, coin.GetNetParams(testNet), privateKey)
+}
+
+func (coin UtxoChain) SignTxOnNetwork(baseTransaction *types.BaseTransaction, network Network, privateKey types.PrivateKey) (*string, error) {
+	netParams, err := networkChainParams(network)
+	if err != nil {
+		return nil, err
+	}
+	return coin.signTx(baseTransaction, netParams, privateKey)
+}
+
+func (coin UtxoChain) signTx(baseTransaction *types.BaseTransaction, netParams chaincfg.Params, privateKey types.PrivateKey) (*string, error) {
+	if coin.config.SigHash != UtxoSigHashForkID {
+		return coin.Sign(baseTransaction, privateKey, netParams)
+	}
+	key, _ := btcec.PrivKeyFromBytes(privateKey)
+	return coin.signForkID(baseTransaction, netParams, func(addr btcutil.Address) (*btcec.PrivateKey, error) {
+		return key, nil
+	})
+}
+
+func (coin UtxoChain) SignMultipleSendAddressTx(baseTransaction *types.BaseTransaction, testNet bool, privateKeys map[string]types.PrivateKey) (*string, error) {
+	netParams := coin.GetNetParams(testNet)
+	if coin.config.SigHash != UtxoSigHashForkID {
+		return coin.SignMultipleSendAddress(baseTransaction, privateKeys, netParams)
+	}
+	return coin.signForkID(baseTransaction, netParams, func(addr btcutil.Address) (*btcec.PrivateKey, error) {
+		privateKey := privateKeys[addr.EncodeAddress()]
+		if privateKey == nil {
+			return nil, errors.ErrorKeyNotFound
+		}
+		key, _ := btcec.PrivKeyFromBytes(privateKey)
+		return key, nil
+	})
+}
+
+func (coin UtxoChain) DecodeTransaction(rawTx string, testnet bool) (interface{}, error) {
+	params := coin.GetNetParams(testnet)
+	return coin.DecodeTx(rawTx, params)
+}
+
+func (coin UtxoChain) EstimateSize(inputCount int, outputAddrs []string, hasExtraChangeAddr bool, testNet bool) int {
+	params := coin.GetNetParams(testNet)
+	return coin.EstimateTxSizes(inputCount, outputAddrs, hasExtraChangeAddr, params)
+}
+
+func (coin UtxoChain) GetNetParams(testNet bool) chaincfg.Params {
+	net := coin.netConfig(testNet)
+	params := chaincfg.MainNetParams
+	params.Name = net.Name
+	params.PubKeyHashAddrID = net.PubKeyHashAddrID
+	params.ScriptHashAddrID = net.ScriptHashAddrID
+	params.PrivateKeyID = net.PrivateKeyID
+	params.Bech32HRPSegwit = net.Bech32HRPSegwit
+	if net.Net != 0 {
+		params.Net = wire.BitcoinNet(net.Net)
+	}
+	return params
+}
+
+func (coin UtxoChain) netConfig(testNet bool) UtxoNetConfig {
+	if testNet && coin.config.TestNet != nil {
+		return *coin.config.TestNet
+	}
+	return coin.config.MainNet
+}
+
+// signForkID signs the p2pkh and p2wpkh inputs with BIP143 and SIGHASH_FORKID,
+// the fork id is placed in the upper 24 bits of the hash type
+func (coin UtxoChain) signForkID(tx *types.BaseTransaction, netParams chaincfg.Params, getKey func(addr btcutil.Address) (*btcec.PrivateKey, error)) (*string, error) {
+	authoredTx := tx.CoinTransaction.(*txauthor.AuthoredTx)
+	prevOuts := make(map[wire.OutPoint]*wire.TxOut, len(authoredTx.Tx.TxIn))
+	for i, txIn := range authoredTx.Tx.TxIn {
+		prevOuts[txIn.PreviousOutPoint] = wire.NewTxOut(int64(authoredTx.PrevInputValues[i]), authoredTx.PrevScripts[i])
+	}
+	sigHashes := txscript.NewTxSigHashes(authoredTx.Tx, txscript.NewMultiPrevOutFetcher(prevOuts))
+	hashType := txscript.SigHashAll | sigHashForkID | txscript.SigHashType(coin.config.ForkID<<8)
+
+	for i, txIn := range authoredTx.Tx.TxIn {
+		pkScript := authoredTx.PrevScripts[i]
+		class, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, &netParams)
+		if err != nil {
+			return nil, err
+		}
+		if class != txscript.PubKeyHashTy && class != txscript.WitnessV0PubKeyHashTy {
+			return nil, fmt.Errorf("can't sign %s input %d", class, i)
+		}
+		key, err := getKey(addrs[0])
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := txscript.CalcWitnessSigHash(pkScript, sigHashes, hashType, authoredTx.Tx, i, int64(authoredTx.PrevInputValues[i]))
+		if err != nil {
+			return nil, err
+		}
+		sig := append(ecdsa.Sign(key, hash).Serialize(), byte(hashType))
+		pubKey := key.PubKey().SerializeCompressed()
+		if class == txscript.WitnessV0PubKeyHashTy {
+			txIn.Witness = wire.TxWitness{sig, pubKey}
+			continue
+		}
+		txIn.SignatureScript, err = txscript.NewScriptBuilder().AddData(sig).AddData(pubKey).Script()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(authoredTx.Tx.SerializeSize())
+	err := authoredTx.Tx.Serialize(&buf)
+	if err != nil {
+		return nil, err
+	}
+	toString := hex.EncodeToString(buf.Bytes())
+	return &toString, nil
+}
@@ -7,7 +7,7 @@ import (
 )
 
 const (
-	XDAI_TEST    = 100
+	XDAI_TEST    = 10200 // Chiado
 	XDAI_MAIN    = 100
 	CurrencyXdai = "XDAI"
 )
@@ -17,6 +17,7 @@ var coinXdai Xdai
 func init() {
 	coinXdai = Xdai{}
 	RegisterEthLikeCoin(coinXdai)
+	registerEvmNetworks(CurrencyXdai, XDAI_MAIN, XDAI_TEST)
 }
 
 type Xdai struct {
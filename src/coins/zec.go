@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/btcsuite/btcwallet/wallet/txrules"
+	"github.com/btcsuite/btcwallet/wallet/txsizes"
 	"strings"
 	"wallet-sdk/src/coins/zecutil"
 	"wallet-sdk/src/deriver"
@@ -16,7 +17,6 @@ import (
 	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/btcsuite/btcd/btcjson"
 	"github.com/btcsuite/btcd/btcutil"
-	"github.com/btcsuite/btcd/btcutil/base58"
 	"github.com/btcsuite/btcd/chaincfg"
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
 	"github.com/btcsuite/btcd/txscript"
@@ -31,6 +31,12 @@ const CurrencyZec = "ZEC"
 type ZecTxParams struct {
 	BtcTxParams
 	ZecLastBlockNum int `json:"zecLastBlockNum"`
+	// ZecTxVersion 4 (Sapling, default) or 5 (NU5)
+	ZecTxVersion int32 `json:"zecTxVersion"`
+	// ZecConsensusBranchId overrides the branch id derived from ZecLastBlockNum
+	ZecConsensusBranchId uint32 `json:"zecConsensusBranchId"`
+	// ZecZip317Fee ignores Fee and pays the ZIP-317 conventional fee, without change the inputs must leave at least that fee
+	ZecZip317Fee bool `json:"zecZip317Fee"`
 }
 
 var coinZec Zec
@@ -119,8 +125,16 @@ func (coin Zec) CreateTransaction(txParams types.TxParams, testNet bool) (*types
 	var changeAddress = extraParams.ChangeAddress
 
 	var totalValuInputs = decimal.New(0, 0)
-	params := getZecNetParams(testNet)
-	newTx := wire.NewMsgTx(4)
+	txVersion := extraParams.ZecTxVersion
+	if txVersion == 0 {
+		txVersion = 4
+	}
+	if txVersion != 4 && txVersion != 5 {
+		return nil, errors.ErrorInvalidInput
+	}
+	newTx := wire.NewMsgTx(txVersion)
+	var prevOutValues []int64
+	var prevOutScripts [][]byte
 	for _, unspend := range unspends {
 		ph, err := chainhash.NewHashFromStr(
 			unspend.TxHash,
@@ -153,7 +167,12 @@ func (coin Zec) CreateTransaction(txParams types.TxParams, testNet bool) (*types
 			Witness:          nil,
 			Sequence:         uint32(outValueSatoshi),
 		}
+		if txVersion == 5 {
+			txIn.Sequence = wire.MaxTxInSequenceNum
+		}
 		newTx.AddTxIn(&txIn)
+		prevOutValues = append(prevOutValues, int64(outValueSatoshi))
+		prevOutScripts = append(prevOutScripts, addrScript)
 		totalValuInputs = totalValuInputs.Add(unspend.TxValue)
 	}
 
@@ -168,20 +187,16 @@ func (coin Zec) CreateTransaction(txParams types.TxParams, testNet bool) (*types
 		} else {
 			netName = "mainnet"
 		}
-		_, err := zecutil.DecodeAddress(receiver.Address, netName)
-		if err != nil {
-			return nil, errors.ErrorInvalidAddress
+		// unified addresses are paid through their transparent receiver
+		addr, err := zecutil.DecodeTransparentAddress(receiver.Address, netName)
+		if err == zecutil.ErrNoTransparentReceiver {
+			return nil, err
 		}
-
-		decoded := base58.Decode(receiver.Address)
-		var addr *btcutil.AddressPubKeyHash
-		addr, err = btcutil.NewAddressPubKeyHash(decoded[2:len(decoded)-4], params.Params)
-
 		if err != nil {
-			return nil, err
+			return nil, errors.ErrorInvalidAddress
 		}
-		outputAddrs = append(outputAddrs, receiver.Address)
-		receiverPkScript, err := txscript.PayToAddrScript(addr)
+		outputAddrs = append(outputAddrs, addr.EncodeAddress())
+		receiverPkScript, err := zecutil.PayToAddrScript(addr)
 		if err != nil {
 			return nil, err
 		}
@@ -203,6 +218,20 @@ func (coin Zec) CreateTransaction(txParams types.TxParams, testNet bool) (*types
 	targetFee := txrules.FeeForSerializeSize(fee, estimateSize)
 	toBTC := targetFee.ToBTC()
 
+	feeValue := extraParams.Fee
+	var conventionalFee int64
+	if extraParams.ZecZip317Fee {
+		txOutTotalSize := 0
+		for _, txOut := range newTx.TxOut {
+			txOutTotalSize += txOut.SerializeSize()
+		}
+		if changeAddress != "" {
+			txOutTotalSize += txsizes.P2PKHOutputSize
+		}
+		conventionalFee = zecutil.ConventionalFee(len(unspends)*txsizes.RedeemP2PKHInputSize, txOutTotalSize)
+		feeValue = decimal.New(conventionalFee, -int32(coin.GetDecimal()))
+	}
+
 	if changeAddress != "" && &changeAddress != nil {
 		change := totalValuInputs.Sub(need).Sub(decimal.NewFromFloat(toBTC))
 		var netName string
@@ -211,24 +240,22 @@ func (coin Zec) CreateTransaction(txParams types.TxParams, testNet bool) (*types
 		} else {
 			netName = "mainnet"
 		}
-		_, err := zecutil.DecodeAddress(changeAddress, netName)
+		decodedChange, err := zecutil.DecodeAddress(changeAddress, netName)
 		if err != nil {
 			return nil, errors.ErrorInvalidAddress
 		}
-		change = totalValuInputs.Sub(need).Sub(decimal.NewFromFloat(extraParams.Fee.InexactFloat64()))
+		change = totalValuInputs.Sub(need).Sub(decimal.NewFromFloat(feeValue.InexactFloat64()))
+		if change.IsNegative() {
+			return nil, fmt.Errorf("%w: the inputs don't cover the outputs and the fee", errors.ErrorInvalidAmount)
+		}
 		outValue, err := btcutil.NewAmount(change.InexactFloat64())
 		if err != nil {
 			return nil, errors.ErrorInvalidAmount
 		}
 
 		changeSatoshi := outValue.ToUnit(btcutil.AmountSatoshi)
-		decoded := base58.Decode(changeAddress)
-		addr, err := btcutil.NewAddressPubKeyHash(decoded[2:len(decoded)-4], params.Params)
-		if err != nil {
-			return nil, err
-		}
-
-		changePkScript, err := txscript.PayToAddrScript(addr)
+		// t3 change is paid to its script hash like the receivers
+		changePkScript, err := zecutil.PayToAddrScript(decodedChange)
 		if err != nil {
 			return nil, err
 		}
@@ -239,9 +266,27 @@ func (coin Zec) CreateTransaction(txParams types.TxParams, testNet bool) (*types
 		}
 	}
 
+	// the fee is what the inputs leave, nodes reject transactions paying less than the ZIP-317 fee
+	impliedFee := int64(0)
+	for _, value := range prevOutValues {
+		impliedFee += value
+	}
+	for _, txOut := range newTx.TxOut {
+		impliedFee -= txOut.Value
+	}
+	if impliedFee < 0 {
+		return nil, fmt.Errorf("%w: the inputs don't cover the outputs", errors.ErrorInvalidAmount)
+	}
+	if impliedFee < conventionalFee {
+		return nil, fmt.Errorf("%w: fee of %d zatoshis, ZIP-317 needs %d", errors.ErrorInvalidFee, impliedFee, conventionalFee)
+	}
+
 	zecTx := &zecutil.MsgTx{
-		MsgTx:        newTx,
-		ExpiryHeight: uint32(extraParams.ZecLastBlockNum),
+		MsgTx:             newTx,
+		ExpiryHeight:      uint32(extraParams.ZecLastBlockNum),
+		ConsensusBranchID: extraParams.ZecConsensusBranchId,
+		PrevOutValues:     prevOutValues,
+		PrevOutScripts:    prevOutScripts,
 	}
 
 	return &types.BaseTransaction{CoinTransaction: zecTx}, nil
@@ -274,9 +319,12 @@ func (coin Zec) DecodeTx(rawTx string, params chaincfg.Params) (interface{}, err
 	firstSHA := sha256.Sum256(rawTxBytes)
 	secondSHA := sha256.Sum256(firstSHA[:])
 
-	txid := reverseBytes(secondSHA[:])
+	txid := hex.EncodeToString(reverseBytes(secondSHA[:]))
+	if mtx.Version == 5 {
+		txid = mtx.TxHash().String()
+	}
 	txReply := btcjson.TxRawDecodeResult{
-		Txid:     hex.EncodeToString(txid),
+		Txid:     txid,
 		Version:  mtx.Version,
 		Locktime: mtx.LockTime,
 		Vin:      coin.createVinList(mtx),
@@ -420,7 +468,7 @@ func (coin Zec) SignTx(baseTransaction *types.BaseTransaction, testNet bool, key
 			}),
 			nil,
 			nil,
-			int64(txIn.Sequence))
+			transaction.InputAmount(index))
 		if err != nil {
 			return nil, err
 		}
@@ -505,7 +553,7 @@ func (coin Zec) SignMultipleSendAddressTx(baseTransaction *types.BaseTransaction
 				return addrScript, nil
 			}),
 			nil,
-			int64(txIn.Sequence))
+			transaction.InputAmount(index))
 		if err != nil {
 			return nil, err
 		}
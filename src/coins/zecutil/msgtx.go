@@ -27,6 +27,30 @@ type scriptFreeList chan []byte
 type MsgTx struct {
 	*wire.MsgTx
 	ExpiryHeight uint32
+	// ConsensusBranchID is serialized in v5 transactions and keys the
+	// signature hash. Zero selects the branch active at ExpiryHeight.
+	ConsensusBranchID uint32
+	// PrevOutValues and PrevOutScripts hold the amount and scriptPubKey of
+	// every spent output, as committed to by the ZIP-244 signature digest.
+	PrevOutValues  []int64
+	PrevOutScripts [][]byte
+}
+
+// BranchID returns the consensus branch id the transaction is bound to.
+func (msg *MsgTx) BranchID() uint32 {
+	if msg.ConsensusBranchID != 0 {
+		return msg.ConsensusBranchID
+	}
+	return branchIDAt(msg.ExpiryHeight)
+}
+
+// InputAmount returns the amount of the output spent by input idx, falling
+// back to the value stashed in the input sequence for v4 transactions.
+func (msg *MsgTx) InputAmount(idx int) int64 {
+	if idx < len(msg.PrevOutValues) {
+		return msg.PrevOutValues[idx]
+	}
+	return int64(msg.TxIn[idx].Sequence)
 }
 
 // witnessMarkerBytes are a pair of bytes specific to the witness encoding. If
@@ -84,6 +108,10 @@ func (c scriptFreeList) Borrow(size uint64) []byte {
 
 // TxHash generates the Hash for the transaction.
 func (msg *MsgTx) TxHash() chainhash.Hash {
+	if msg.Version == versionNu5 {
+		h, _ := v5TxID(msg)
+		return h
+	}
 	var buf bytes.Buffer
 	_ = msg.ZecEncode(&buf, 0, wire.BaseEncoding)
 	return chainhash.DoubleHashH(buf.Bytes())
@@ -93,8 +121,11 @@ func (msg *MsgTx) TxHash() chainhash.Hash {
 // This is part of the Message interface implementation.
 // See Serialize for encoding transactions to be stored to disk, such as in a
 // database, as opposed to encoding transactions for the wire.
-// msg.Version must be 3 or 4 and may or may not include the overwintered flag
+// msg.Version must be 3, 4 or 5 and may or may not include the overwintered flag
 func (msg *MsgTx) ZecEncode(w io.Writer, pver uint32, enc wire.MessageEncoding) error {
+	if msg.Version == versionNu5 {
+		return msg.zecEncodeV5(w, pver)
+	}
 	err := binarySerializer.PutUint32(w, littleEndian, uint32(msg.Version)|(1<<31))
 	if err != nil {
 		return err
@@ -195,6 +226,47 @@ func (msg *MsgTx) ZecEncode(w io.Writer, pver uint32, enc wire.MessageEncoding)
 	return WriteVarInt(w, pver, 0)
 }
 
+// zecEncodeV5 encodes the receiver to w using the ZIP-225 v5 transaction
+// format. Only the transparent bundle is supported, the sapling and orchard
+// bundles are always written empty.
+func (msg *MsgTx) zecEncodeV5(w io.Writer, pver uint32) error {
+	for _, v := range []uint32{uint32(msg.Version) | (1 << 31), versionNu5GroupID, msg.BranchID(), msg.LockTime, msg.ExpiryHeight} {
+		if err := binarySerializer.PutUint32(w, littleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	err := WriteVarInt(w, pver, uint64(len(msg.TxIn)))
+	if err != nil {
+		return err
+	}
+	for _, ti := range msg.TxIn {
+		err = writeTxIn(w, pver, msg.Version, ti)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = WriteVarInt(w, pver, uint64(len(msg.TxOut)))
+	if err != nil {
+		return err
+	}
+	for _, to := range msg.TxOut {
+		err = WriteTxOut(w, pver, msg.Version, to)
+		if err != nil {
+			return err
+		}
+	}
+
+	// nSpendsSapling, nOutputsSapling, nActionsOrchard
+	for i := 0; i < 3; i++ {
+		if err = WriteVarInt(w, pver, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // WriteTxOut encodes to into the bsv protocol encoding for a transaction
 // output (TxOut) to w.
 //
@@ -222,7 +294,22 @@ func (msg *MsgTx) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding)
 	if err != nil {
 		return err
 	}
-	fmt.Println(groupId)
+
+	if msg.Version == versionNu5 {
+		if groupId != versionNu5GroupID {
+			str := fmt.Sprintf("unexpected version group id %x", groupId)
+			return messageError("MsgTx.BtcDecode", str)
+		}
+		if msg.ConsensusBranchID, err = binarySerializer.Uint32(r, littleEndian); err != nil {
+			return err
+		}
+		if msg.LockTime, err = binarySerializer.Uint32(r, littleEndian); err != nil {
+			return err
+		}
+		if msg.ExpiryHeight, err = binarySerializer.Uint32(r, littleEndian); err != nil {
+			return err
+		}
+	}
 
 	count, err := wire.ReadVarInt(r, pver)
 	if err != nil {
@@ -383,10 +470,25 @@ func (msg *MsgTx) BtcDecode(r io.Reader, pver uint32, enc wire.MessageEncoding)
 		}
 	}
 
-	msg.LockTime, err = binarySerializer.Uint32(r, littleEndian)
-	if err != nil {
-		returnScriptBuffers()
-		return err
+	if msg.Version == versionNu5 {
+		// nSpendsSapling, nOutputsSapling, nActionsOrchard
+		for i := 0; i < 3; i++ {
+			n, err := wire.ReadVarInt(r, pver)
+			if err != nil {
+				returnScriptBuffers()
+				return err
+			}
+			if n != 0 {
+				returnScriptBuffers()
+				return messageError("MsgTx.BtcDecode", "shielded components are not supported")
+			}
+		}
+	} else {
+		msg.LockTime, err = binarySerializer.Uint32(r, littleEndian)
+		if err != nil {
+			returnScriptBuffers()
+			return err
+		}
 	}
 
 	// Create a single allocation to house all of the scripts and set each
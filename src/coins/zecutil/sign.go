@@ -31,11 +31,13 @@ const (
 const (
 	versionOverwinter int32 = 3
 	versionSapling          = 4
+	versionNu5              = 5
 )
 
 const (
 	versionOverwinterGroupID uint32 = 0x3C48270
 	versionSaplingGroupID           = 0x892f2085
+	versionNu5GroupID               = 0x26A7270A
 )
 
 // https://github.com/zcash/zcash/blob/89f5ee5dec3fdfd70202baeaf74f09fa32bfb1a8/src/chainparams.cpp#L99
@@ -51,6 +53,7 @@ var upgradeParams = []upgradeParam{
 	//{1786531, []byte{0x21, 0x96, 0x51, 0x37}},
 	//{1842420, []byte{0xB4, 0xD0, 0xD6, 0xC2}},
 	{1687104, []byte{0xB4, 0xD0, 0xD6, 0xC2}},
+	{2726400, []byte{0x55, 0x10, 0xE7, 0xC8}},
 }
 
 // RawTxInSignature returns the serialized ECDSA signature for the input idx of
@@ -63,11 +66,16 @@ func RawTxInSignature(
 	key *btcec.PrivateKey,
 	amt int64,
 ) (_ []byte, err error) {
-	var cache *txscript.TxSigHashes
-	if cache, err = NewTxSigHashes(tx); err != nil {
-		return nil, err
+	var bHash []byte
+	if tx.Version == versionNu5 {
+		bHash, err = zip244SignatureHash(subScript, hashType, tx, idx, amt)
+	} else {
+		var cache *txscript.TxSigHashes
+		if cache, err = NewTxSigHashes(tx); err != nil {
+			return nil, err
+		}
+		bHash, err = blake2bSignatureHash(subScript, cache, hashType, tx, idx, amt)
 	}
-	bHash, err := blake2bSignatureHash(subScript, cache, hashType, tx, idx, amt)
 	if err != nil {
 		return nil, err
 	}
@@ -153,8 +161,8 @@ func SignTxOutput(
 	return mergedScript, nil
 }
 
-// sigHashKey return blake2b key by current height
-func sigHashKey(activationHeight uint32) []byte {
+// branchIDAt return the consensus branch id active at the given height
+func branchIDAt(activationHeight uint32) uint32 {
 	var i int
 	for i = len(upgradeParams) - 1; i >= 0; i-- {
 		if activationHeight >= upgradeParams[i].ActivationHeight {
@@ -162,7 +170,14 @@ func sigHashKey(activationHeight uint32) []byte {
 		}
 	}
 
-	return append([]byte(blake2BSigHash), upgradeParams[i].BranchID...)
+	return binary.LittleEndian.Uint32(upgradeParams[i].BranchID)
+}
+
+// sigHashKey return blake2b key by consensus branch id
+func sigHashKey(branchID uint32) []byte {
+	var bBranchID [4]byte
+	binary.LittleEndian.PutUint32(bBranchID[:], branchID)
+	return append([]byte(blake2BSigHash), bBranchID[:]...)
 }
 
 // blake2bSignatureHash
@@ -316,7 +331,7 @@ func blake2bSignatureHash(
 
 	var h chainhash.Hash
 
-	if h, err = blake2bHash(sigHash.Bytes(), sigHashKey(tx.ExpiryHeight)); err != nil {
+	if h, err = blake2bHash(sigHash.Bytes(), sigHashKey(tx.BranchID())); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,223 @@
+package zecutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/btcsuite/btcd/wire"
+	blake2 "github.com/dchest/blake2b"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// https://zips.z.cash/zip-0316
+const (
+	TypecodeP2PKH   = 0x00
+	TypecodeP2SH    = 0x01
+	TypecodeSapling = 0x02
+	TypecodeOrchard = 0x03
+
+	uaPaddingSize      = 16
+	f4JumbleMinLength  = 48
+	f4JumbleMaxLength  = 4194368
+	f4JumbleHashLength = 64
+)
+
+var ErrNoTransparentReceiver = errors.New("unified address has no transparent receiver")
+
+// UnifiedAddress is a decoded ZIP-316 unified address.
+type UnifiedAddress struct {
+	P2PKH   []byte
+	P2SH    []byte
+	Sapling []byte
+	Orchard []byte
+	// Unknown holds receivers with typecodes this package does not understand
+	Unknown map[uint64][]byte
+
+	netName string
+}
+
+// IsUnifiedAddress reports whether address carries the unified address hrp of netName
+func IsUnifiedAddress(address string, netName string) bool {
+	net, ok := NetList[netName]
+	if !ok {
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(address), net.UnifiedAddressHRP+"1")
+}
+
+// DecodeUnifiedAddress decodes a bech32m, F4Jumbled unified address
+func DecodeUnifiedAddress(address string, netName string) (*UnifiedAddress, error) {
+	net, ok := NetList[netName]
+	if !ok {
+		return nil, errors.New("unknown net")
+	}
+
+	hrp, data, err := bech32.DecodeNoLimit(address)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != net.UnifiedAddressHRP {
+		return nil, fmt.Errorf("unexpected unified address hrp %s", hrp)
+	}
+	// DecodeNoLimit accepts both checksum variants, unified addresses must use bech32m
+	if encoded, err := bech32.EncodeM(hrp, data); err != nil || encoded != strings.ToLower(address) {
+		return nil, errors.New("unified address must be bech32m encoded")
+	}
+	jumbled, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := f4JumbleInv(jumbled)
+	if err != nil {
+		return nil, err
+	}
+	var padding [uaPaddingSize]byte
+	copy(padding[:], hrp)
+	if !bytes.Equal(raw[len(raw)-uaPaddingSize:], padding[:]) {
+		return nil, errors.New("invalid unified address padding")
+	}
+
+	ua := &UnifiedAddress{Unknown: map[uint64][]byte{}, netName: netName}
+	r := bytes.NewReader(raw[:len(raw)-uaPaddingSize])
+	seen := map[uint64]bool{}
+	for r.Len() > 0 {
+		typecode, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return nil, err
+		}
+		length, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return nil, err
+		}
+		if length > uint64(r.Len()) {
+			return nil, errors.New("truncated unified address receiver")
+		}
+		value := make([]byte, length)
+		if _, err = r.Read(value); err != nil {
+			return nil, err
+		}
+		if seen[typecode] {
+			return nil, fmt.Errorf("duplicate unified address typecode %d", typecode)
+		}
+		seen[typecode] = true
+
+		switch typecode {
+		case TypecodeP2PKH:
+			ua.P2PKH = value
+		case TypecodeP2SH:
+			ua.P2SH = value
+		case TypecodeSapling:
+			ua.Sapling = value
+		case TypecodeOrchard:
+			ua.Orchard = value
+		default:
+			ua.Unknown[typecode] = value
+		}
+	}
+
+	if ua.P2PKH != nil && ua.P2SH != nil {
+		return nil, errors.New("unified address contains both p2pkh and p2sh receivers")
+	}
+	if (ua.P2PKH != nil && len(ua.P2PKH) != ripemd160.Size) || (ua.P2SH != nil && len(ua.P2SH) != ripemd160.Size) {
+		return nil, errors.New("incorrect transparent receiver len")
+	}
+	if (ua.Sapling != nil && len(ua.Sapling) != 43) || (ua.Orchard != nil && len(ua.Orchard) != 43) {
+		return nil, errors.New("incorrect shielded receiver len")
+	}
+	if ua.Sapling == nil && ua.Orchard == nil && len(ua.Unknown) == 0 {
+		return nil, errors.New("unified address has no shielded receiver")
+	}
+	return ua, nil
+}
+
+// TransparentAddress returns the transparent receiver of the unified address
+func (ua *UnifiedAddress) TransparentAddress() (btcutil.Address, error) {
+	switch {
+	case ua.P2PKH != nil:
+		addr := &ZecAddressPubKeyHash{prefix: ua.netName}
+		copy(addr.hash[:], ua.P2PKH)
+		return addr, nil
+	case ua.P2SH != nil:
+		addr := &ZecAddressScriptHash{prefix: ua.netName}
+		copy(addr.hash[:], ua.P2SH)
+		return addr, nil
+	}
+	return nil, ErrNoTransparentReceiver
+}
+
+// DecodeTransparentAddress decodes a t-address, or the transparent receiver of a unified address
+func DecodeTransparentAddress(address string, netName string) (btcutil.Address, error) {
+	if !IsUnifiedAddress(address, netName) {
+		return DecodeAddress(address, netName)
+	}
+	ua, err := DecodeUnifiedAddress(address, netName)
+	if err != nil {
+		return nil, err
+	}
+	return ua.TransparentAddress()
+}
+
+// f4JumbleInv reverses the F4Jumble permutation applied to unified encodings
+func f4JumbleInv(message []byte) ([]byte, error) {
+	if len(message) < f4JumbleMinLength || len(message) > f4JumbleMaxLength {
+		return nil, fmt.Errorf("invalid unified address length %d", len(message))
+	}
+	leftLen := len(message) / 2
+	if leftLen > f4JumbleHashLength {
+		leftLen = f4JumbleHashLength
+	}
+	c := append([]byte{}, message[:leftLen]...)
+	d := append([]byte{}, message[leftLen:]...)
+
+	// y = c ^ H(1, d), x = d ^ G(1, y), a = y ^ H(0, x), b = x ^ G(0, a)
+	for _, round := range []byte{1, 0} {
+		h, err := f4JumbleH(round, d, leftLen)
+		if err != nil {
+			return nil, err
+		}
+		xorBytes(c, h)
+		g, err := f4JumbleG(round, c, len(d))
+		if err != nil {
+			return nil, err
+		}
+		xorBytes(d, g)
+	}
+	return append(c, d...), nil
+}
+
+func f4JumbleH(round byte, u []byte, size int) ([]byte, error) {
+	person := append([]byte("UA_F4Jumble_H"), round, 0, 0)
+	hash, err := blake2.New(&blake2.Config{Person: person, Size: uint8(size)})
+	if err != nil {
+		return nil, err
+	}
+	hash.Write(u)
+	return hash.Sum(nil), nil
+}
+
+func f4JumbleG(round byte, u []byte, size int) ([]byte, error) {
+	var out []byte
+	for j := 0; len(out) < size; j++ {
+		person := append([]byte("UA_F4Jumble_G"), round, 0, 0)
+		binary.LittleEndian.PutUint16(person[14:], uint16(j))
+		hash, err := blake2.New(&blake2.Config{Person: person, Size: f4JumbleHashLength})
+		if err != nil {
+			return nil, err
+		}
+		hash.Write(u)
+		out = hash.Sum(out)
+	}
+	return out[:size], nil
+}
+
+func xorBytes(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
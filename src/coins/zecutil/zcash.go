@@ -36,6 +36,8 @@ var (
 			{653600, []byte{0x60, 0x0E, 0xB4, 0x2B}},
 			{903000, []byte{0x0B, 0x23, 0xB9, 0xF5}},
 			{1046400, []byte{0xA6, 0x75, 0xff, 0xe9}},
+			{1687104, []byte{0xB4, 0xD0, 0xD6, 0xC2}},
+			{2726400, []byte{0x55, 0x10, 0xE7, 0xC8}},
 		},
 	}
 
@@ -53,6 +55,8 @@ var (
 			{903800, []byte{0x0B, 0x23, 0xB9, 0xF5}},
 			{1028500, []byte{0xA6, 0x75, 0xff, 0xe9}},
 			{1590000, []byte{0x21, 0x96, 0x51, 0x37}},
+			{1842420, []byte{0xB4, 0xD0, 0xD6, 0xC2}},
+			{2976000, []byte{0x55, 0x10, 0xE7, 0xC8}},
 		},
 	}
 
@@ -70,6 +74,8 @@ var (
 			{40, []byte{0x0B, 0x23, 0xB9, 0xF5}},
 			{50, []byte{0xA6, 0x75, 0xff, 0xe9}},
 			{60, []byte{0x21, 0x96, 0x51, 0x37}},
+			{70, []byte{0xB4, 0xD0, 0xD6, 0xC2}},
+			{80, []byte{0x55, 0x10, 0xE7, 0xC8}},
 		},
 	}
 )
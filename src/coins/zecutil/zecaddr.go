@@ -13,23 +13,32 @@ import (
 type ChainParams struct {
 	PubHashPrefixes    []byte
 	ScriptHashPrefixes []byte
+	UnifiedAddressHRP  string
 }
 
 var (
 	MainNet = ChainParams{
 		PubHashPrefixes:    []byte{0x1C, 0xB8},
 		ScriptHashPrefixes: []byte{0x1C, 0xBD},
+		UnifiedAddressHRP:  "u",
 	}
 
 	TestNet3 = ChainParams{
 		PubHashPrefixes:    []byte{0x1D, 0x25},
 		ScriptHashPrefixes: []byte{0x1C, 0xBA},
+		UnifiedAddressHRP:  "utest",
+	}
+
+	RegTest = ChainParams{
+		PubHashPrefixes:    []byte{0x1D, 0x25},
+		ScriptHashPrefixes: []byte{0x1C, 0xBA},
+		UnifiedAddressHRP:  "uregtest",
 	}
 
 	NetList = map[string]ChainParams{
 		"mainnet":  MainNet,
 		"testnet3": TestNet3,
-		"regtest":  TestNet3,
+		"regtest":  RegTest,
 	}
 )
 
@@ -0,0 +1,262 @@
+package zecutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// https://zips.z.cash/zip-0244
+const (
+	txHashPersonalization            = "ZcashTxHash_"
+	headersHashPersonalization       = "ZTxIdHeadersHash"
+	transparentHashPersonalization   = "ZTxIdTranspaHash"
+	prevoutsV5HashPersonalization    = "ZTxIdPrevoutHash"
+	sequenceV5HashPersonalization    = "ZTxIdSequencHash"
+	outputsV5HashPersonalization     = "ZTxIdOutputsHash"
+	amountsHashPersonalization       = "ZTxTrAmountsHash"
+	scriptPubKeysHashPersonalization = "ZTxTrScriptsHash"
+	txInHashPersonalization          = "Zcash___TxInHash"
+	saplingHashPersonalization       = "ZTxIdSaplingHash"
+	orchardHashPersonalization       = "ZTxIdOrchardHash"
+)
+
+// txHashKey return the ZIP-244 blake2b personalization for the tx branch
+func txHashKey(branchID uint32) []byte {
+	var bBranchID [4]byte
+	binary.LittleEndian.PutUint32(bBranchID[:], branchID)
+	return append([]byte(txHashPersonalization), bBranchID[:]...)
+}
+
+// v5HeaderDigest computes the T.1 header digest of a v5 transaction.
+func v5HeaderDigest(tx *MsgTx) (chainhash.Hash, error) {
+	var b bytes.Buffer
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(tx.Version)|(1<<31))
+	b.Write(buf[:])
+	binary.LittleEndian.PutUint32(buf[:], versionNu5GroupID)
+	b.Write(buf[:])
+	binary.LittleEndian.PutUint32(buf[:], tx.BranchID())
+	b.Write(buf[:])
+	binary.LittleEndian.PutUint32(buf[:], tx.LockTime)
+	b.Write(buf[:])
+	binary.LittleEndian.PutUint32(buf[:], tx.ExpiryHeight)
+	b.Write(buf[:])
+	return blake2bHash(b.Bytes(), []byte(headersHashPersonalization))
+}
+
+// v5PrevoutsDigest computes the T.2a prevouts digest.
+func v5PrevoutsDigest(tx *MsgTx) (chainhash.Hash, error) {
+	var b bytes.Buffer
+	for _, in := range tx.TxIn {
+		if err := writeOutPoint(&b, 0, tx.Version, &in.PreviousOutPoint); err != nil {
+			return chainhash.Hash{}, err
+		}
+	}
+	return blake2bHash(b.Bytes(), []byte(prevoutsV5HashPersonalization))
+}
+
+// v5SequenceDigest computes the T.2b sequence digest.
+func v5SequenceDigest(tx *MsgTx) (chainhash.Hash, error) {
+	var b bytes.Buffer
+	for _, in := range tx.TxIn {
+		var buf [4]byte
+		binary.LittleEndian.PutUint32(buf[:], in.Sequence)
+		b.Write(buf[:])
+	}
+	return blake2bHash(b.Bytes(), []byte(sequenceV5HashPersonalization))
+}
+
+// v5OutputsDigest computes the T.2c outputs digest over the given outputs.
+func v5OutputsDigest(outs []*wire.TxOut) (chainhash.Hash, error) {
+	var b bytes.Buffer
+	for _, out := range outs {
+		if err := WriteTxOut(&b, 0, 0, out); err != nil {
+			return chainhash.Hash{}, err
+		}
+	}
+	return blake2bHash(b.Bytes(), []byte(outputsV5HashPersonalization))
+}
+
+// v5TransparentDigest computes the T.2 transparent digest used by the txid.
+func v5TransparentDigest(tx *MsgTx) (chainhash.Hash, error) {
+	if len(tx.TxIn) == 0 && len(tx.TxOut) == 0 {
+		return blake2bHash(nil, []byte(transparentHashPersonalization))
+	}
+	prevouts, err := v5PrevoutsDigest(tx)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	sequence, err := v5SequenceDigest(tx)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	outputs, err := v5OutputsDigest(tx.TxOut)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	var b bytes.Buffer
+	b.Write(prevouts[:])
+	b.Write(sequence[:])
+	b.Write(outputs[:])
+	return blake2bHash(b.Bytes(), []byte(transparentHashPersonalization))
+}
+
+// v5TxDigest combines the header, transparent and (empty) shielded digests
+// under the branch specific personalization.
+func v5TxDigest(tx *MsgTx, transparent chainhash.Hash) (chainhash.Hash, error) {
+	header, err := v5HeaderDigest(tx)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	sapling, err := blake2bHash(nil, []byte(saplingHashPersonalization))
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	orchard, err := blake2bHash(nil, []byte(orchardHashPersonalization))
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	var b bytes.Buffer
+	b.Write(header[:])
+	b.Write(transparent[:])
+	b.Write(sapling[:])
+	b.Write(orchard[:])
+	return blake2bHash(b.Bytes(), txHashKey(tx.BranchID()))
+}
+
+// v5TxID computes the ZIP-244 transaction identifier of a v5 transaction.
+func v5TxID(tx *MsgTx) (chainhash.Hash, error) {
+	transparent, err := v5TransparentDigest(tx)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	return v5TxDigest(tx, transparent)
+}
+
+// zip244SignatureHash computes the ZIP-244 signature digest for the
+// transparent input idx of a v5 transaction.
+func zip244SignatureHash(
+	subScript []byte,
+	hashType txscript.SigHashType,
+	tx *MsgTx,
+	idx int,
+	amt int64,
+) (_ []byte, err error) {
+	if idx > len(tx.TxIn)-1 {
+		return nil, fmt.Errorf("zip244SignatureHash error: idx %d but %d txins", idx, len(tx.TxIn))
+	}
+	switch hashType {
+	case txscript.SigHashAll, txscript.SigHashNone, txscript.SigHashSingle,
+		txscript.SigHashAll | txscript.SigHashAnyOneCanPay,
+		txscript.SigHashNone | txscript.SigHashAnyOneCanPay,
+		txscript.SigHashSingle | txscript.SigHashAnyOneCanPay:
+	default:
+		return nil, fmt.Errorf("zip244SignatureHash error: invalid hash type 0x%x", uint32(hashType))
+	}
+	anyoneCanPay := hashType&txscript.SigHashAnyOneCanPay != 0
+
+	var (
+		prevouts, amounts, scriptPubKeys, sequence, outputs, txIn chainhash.Hash
+		b                                                         bytes.Buffer
+	)
+
+	// << prevouts_sig_digest, amounts_sig_digest, scriptpubkeys_sig_digest, sequence_sig_digest
+	if anyoneCanPay {
+		if prevouts, err = blake2bHash(nil, []byte(prevoutsV5HashPersonalization)); err != nil {
+			return nil, err
+		}
+		if amounts, err = blake2bHash(nil, []byte(amountsHashPersonalization)); err != nil {
+			return nil, err
+		}
+		if scriptPubKeys, err = blake2bHash(nil, []byte(scriptPubKeysHashPersonalization)); err != nil {
+			return nil, err
+		}
+		if sequence, err = blake2bHash(nil, []byte(sequenceV5HashPersonalization)); err != nil {
+			return nil, err
+		}
+	} else {
+		if len(tx.PrevOutValues) != len(tx.TxIn) || len(tx.PrevOutScripts) != len(tx.TxIn) {
+			return nil, fmt.Errorf("zip244SignatureHash error: the amount and script of all %d txins are required", len(tx.TxIn))
+		}
+		if prevouts, err = v5PrevoutsDigest(tx); err != nil {
+			return nil, err
+		}
+		for _, value := range tx.PrevOutValues {
+			var buf [8]byte
+			binary.LittleEndian.PutUint64(buf[:], uint64(value))
+			b.Write(buf[:])
+		}
+		if amounts, err = blake2bHash(b.Bytes(), []byte(amountsHashPersonalization)); err != nil {
+			return nil, err
+		}
+		b.Reset()
+		for _, script := range tx.PrevOutScripts {
+			if err = WriteVarBytes(&b, 0, script); err != nil {
+				return nil, err
+			}
+		}
+		if scriptPubKeys, err = blake2bHash(b.Bytes(), []byte(scriptPubKeysHashPersonalization)); err != nil {
+			return nil, err
+		}
+		b.Reset()
+		if sequence, err = v5SequenceDigest(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	// << outputs_sig_digest
+	switch {
+	case hashType&sigHashMask == txscript.SigHashSingle && idx < len(tx.TxOut):
+		outputs, err = v5OutputsDigest(tx.TxOut[idx : idx+1])
+	case hashType&sigHashMask == txscript.SigHashSingle || hashType&sigHashMask == txscript.SigHashNone:
+		outputs, err = v5OutputsDigest(nil)
+	default:
+		outputs, err = v5OutputsDigest(tx.TxOut)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// << txin_sig_digest
+	if err = writeOutPoint(&b, 0, tx.Version, &tx.TxIn[idx].PreviousOutPoint); err != nil {
+		return nil, err
+	}
+	if err = binary.Write(&b, binary.LittleEndian, amt); err != nil {
+		return nil, err
+	}
+	if err = WriteVarBytes(&b, 0, subScript); err != nil {
+		return nil, err
+	}
+	var bSequence [4]byte
+	binary.LittleEndian.PutUint32(bSequence[:], tx.TxIn[idx].Sequence)
+	b.Write(bSequence[:])
+	if txIn, err = blake2bHash(b.Bytes(), []byte(txInHashPersonalization)); err != nil {
+		return nil, err
+	}
+	b.Reset()
+
+	b.WriteByte(byte(hashType))
+	b.Write(prevouts[:])
+	b.Write(amounts[:])
+	b.Write(scriptPubKeys[:])
+	b.Write(sequence[:])
+	b.Write(outputs[:])
+	b.Write(txIn[:])
+	transparent, err := blake2bHash(b.Bytes(), []byte(transparentHashPersonalization))
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := v5TxDigest(tx, transparent)
+	if err != nil {
+		return nil, err
+	}
+	return h.CloneBytes(), nil
+}
@@ -0,0 +1,26 @@
+package zecutil
+
+// https://zips.z.cash/zip-0317
+const (
+	MarginalFee             = 5000
+	GraceActions            = 2
+	P2pkhStandardInputSize  = 150
+	P2pkhStandardOutputSize = 34
+)
+
+// ConventionalFee returns the ZIP-317 conventional fee in zatoshis for a
+// transparent-only transaction with the given total input and output sizes.
+func ConventionalFee(txInTotalSize, txOutTotalSize int) int64 {
+	logicalActions := ceilDiv(txInTotalSize, P2pkhStandardInputSize)
+	if outActions := ceilDiv(txOutTotalSize, P2pkhStandardOutputSize); outActions > logicalActions {
+		logicalActions = outActions
+	}
+	if logicalActions < GraceActions {
+		logicalActions = GraceActions
+	}
+	return int64(MarginalFee * logicalActions)
+}
+
+func ceilDiv(num, den int) int {
+	return (num + den - 1) / den
+}
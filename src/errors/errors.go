@@ -25,3 +25,35 @@ var ErrorInvalidInput = errors.New("invalid input")
 var ErrorInvalidContractAddress = errors.New("invalid contract address")
 
 var ErrorKeyNotFound = errors.New("key not found")
+
+var ErrorInsufficientTokens = errors.New("insufficient tokens available to construct transaction")
+
+var ErrorNotTokenAwareAddress = errors.New("address is not token-aware")
+
+var ErrorCurrencyAlreadyRegistered = errors.New("currency already registered")
+
+var ErrorNetworkNotSupported = errors.New("network not supported")
+
+var ErrorInvalidFee = errors.New("invalid fee")
+
+var ErrorGasLimitTooLow = errors.New("gas limit below intrinsic gas")
+
+var ErrorChainIdMismatch = errors.New("chain id mismatch")
+
+var ErrorInvalidSignature = errors.New("invalid signature")
+
+var ErrorMessageExpired = errors.New("message expired")
+
+var ErrorMessageNotYetValid = errors.New("message not yet valid")
+
+var ErrorDomainMismatch = errors.New("domain mismatch")
+
+var ErrorNonceMismatch = errors.New("nonce mismatch")
+
+var ErrorInvalidChecksum = errors.New("invalid address checksum")
+
+var ErrorUnknownToken = errors.New("unknown token")
+
+var ErrorTokenDecimalsMismatch = errors.New("token decimals mismatch")
+
+var ErrorNetworkAlreadyRegistered = errors.New("network already registered")
@@ -0,0 +1,95 @@
+package safe
+
+import (
+	"fmt"
+	"math/big"
+	"wallet-sdk/src/coins"
+	"wallet-sdk/src/errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// canonical MultiSend deployments, MultiSendCallOnly refuses delegate calls
+const (
+	MultiSendV130Address         = "0xA238CBeb142c10Ef7Ad8442C6D1f9E89e07e7761"
+	MultiSendCallOnlyV130Address = "0x40A2aCCbd92BCA938b02010E17A5b8929b49130D"
+	MultiSendV141Address         = "0x38869bf66a61cF6bDB996A6aE40D5853Fd43B526"
+	MultiSendCallOnlyV141Address = "0x9641d764fc13c8B624c04430C7356C1C7C8102e2"
+)
+
+// multiSendSelector is the selector of multiSend(bytes)
+var multiSendSelector = hexutil.MustDecode("0x8d80ff0a")
+
+// MultiSendCall is one call of a MultiSend batch
+type MultiSendCall struct {
+	Operation Operation     `json:"operation"`
+	To        string        `json:"to"`
+	Value     *big.Int      `json:"value"`
+	Data      hexutil.Bytes `json:"data"`
+}
+
+// MultiSendData encodes the multiSend call running calls in order on the chain chainId
+func MultiSendData(calls []MultiSendCall, chainId *big.Int) ([]byte, error) {
+	if len(calls) == 0 {
+		return nil, errors.ErrorInvalidInput
+	}
+	var transactions []byte
+	for _, call := range calls {
+		if err := coins.ValidateAddressOnChain(call.To, chainId); err != nil {
+			return nil, err
+		}
+		if call.Operation != Call && call.Operation != DelegateCall {
+			return nil, fmt.Errorf("%w: unknown operation %d", errors.ErrorInvalidInput, call.Operation)
+		}
+		value := bigOrZero(call.Value)
+		if value.Sign() < 0 || value.BitLen() > 256 {
+			return nil, errors.ErrorInvalidAmount
+		}
+		transactions = append(transactions, byte(call.Operation))
+		transactions = append(transactions, common.HexToAddress(call.To).Bytes()...)
+		transactions = append(transactions, common.LeftPadBytes(value.Bytes(), 32)...)
+		transactions = append(transactions, common.LeftPadBytes(big.NewInt(int64(len(call.Data))).Bytes(), 32)...)
+		transactions = append(transactions, call.Data...)
+	}
+
+	// multiSend(bytes transactions), the only argument is dynamic
+	data := append([]byte{}, multiSendSelector...)
+	data = append(data, common.LeftPadBytes(big.NewInt(32).Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(int64(len(transactions))).Bytes(), 32)...)
+	data = append(data, transactions...)
+	if padding := len(transactions) % 32; padding != 0 {
+		data = append(data, make([]byte, 32-padding)...)
+	}
+	return data, nil
+}
+
+// NewMultiSendTransaction returns a SafeTx delegate calling the MultiSend contract of the Safe version,
+// MultiSendCallOnly is used unless one of the calls is a delegate call
+func (safe Safe) NewMultiSendTransaction(calls []MultiSendCall, nonce *big.Int) (*Transaction, error) {
+	data, err := MultiSendData(calls, safe.ChainId)
+	if err != nil {
+		return nil, err
+	}
+	callOnly := true
+	for _, call := range calls {
+		callOnly = callOnly && call.Operation == Call
+	}
+	var multiSend string
+	switch {
+	case safe.Version == Version130 && callOnly:
+		multiSend = MultiSendCallOnlyV130Address
+	case safe.Version == Version130:
+		multiSend = MultiSendV130Address
+	case callOnly:
+		multiSend = MultiSendCallOnlyV141Address
+	default:
+		multiSend = MultiSendV141Address
+	}
+	return &Transaction{
+		To:        multiSend,
+		Data:      data,
+		Operation: DelegateCall,
+		Nonce:     nonce,
+	}, nil
+}
@@ -0,0 +1,302 @@
+package safe
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"wallet-sdk/src/coins"
+	"wallet-sdk/src/errors"
+	"wallet-sdk/src/signer/ethereum_signer"
+	"wallet-sdk/src/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/shopspring/decimal"
+)
+
+// supported Safe contract versions
+const (
+	Version130 = "1.3.0"
+	Version141 = "1.4.1"
+)
+
+type Operation uint8
+
+const (
+	Call         Operation = 0
+	DelegateCall Operation = 1
+)
+
+// SignatureType is how an owner signs a SafeTx
+type SignatureType string
+
+const (
+	// SignatureEIP712 signs the SafeTx hash directly, as eth_signTypedData does
+	SignatureEIP712 SignatureType = "eip712"
+	// SignatureEthSign signs the SafeTx hash as a personal message, v is shifted by 4 as the Safe expects
+	SignatureEthSign SignatureType = "eth_sign"
+)
+
+const execTransactionAbi = `[{"type":"function","name":"execTransaction","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"},{"name":"operation","type":"uint8"},{"name":"safeTxGas","type":"uint256"},{"name":"baseGas","type":"uint256"},{"name":"gasPrice","type":"uint256"},{"name":"gasToken","type":"address"},{"name":"refundReceiver","type":"address"},{"name":"signatures","type":"bytes"}]}]`
+
+var safeTxType = []ethereum_signer.Type{
+	{Name: "to", Type: "address"},
+	{Name: "value", Type: "uint256"},
+	{Name: "data", Type: "bytes"},
+	{Name: "operation", Type: "uint8"},
+	{Name: "safeTxGas", Type: "uint256"},
+	{Name: "baseGas", Type: "uint256"},
+	{Name: "gasPrice", Type: "uint256"},
+	{Name: "gasToken", Type: "address"},
+	{Name: "refundReceiver", Type: "address"},
+	{Name: "nonce", Type: "uint256"},
+}
+
+// Safe is a Safe contract deployed at Address on the chain ChainId
+type Safe struct {
+	Address string   `json:"address"`
+	Version string   `json:"version"`
+	ChainId *big.Int `json:"chainId"`
+}
+
+// Transaction is a SafeTx, empty gas fields and addresses are 0
+type Transaction struct {
+	To             string        `json:"to"`
+	Value          *big.Int      `json:"value"`
+	Data           hexutil.Bytes `json:"data"`
+	Operation      Operation     `json:"operation"`
+	SafeTxGas      *big.Int      `json:"safeTxGas"`
+	BaseGas        *big.Int      `json:"baseGas"`
+	GasPrice       *big.Int      `json:"gasPrice"`
+	GasToken       string        `json:"gasToken"`
+	RefundReceiver string        `json:"refundReceiver"`
+	Nonce          *big.Int      `json:"nonce"`
+}
+
+// Signature is the signature of one owner, 65 bytes r || s || v in the Safe encoding
+type Signature struct {
+	Owner string        `json:"owner"`
+	Data  hexutil.Bytes `json:"data"`
+}
+
+func NewSafe(address string, version string, chainId *big.Int) (*Safe, error) {
+	if err := coins.ValidateAddressOnChain(address, chainId); err != nil {
+		return nil, err
+	}
+	if version != Version130 && version != Version141 {
+		return nil, fmt.Errorf("%w: unsupported safe version %s", errors.ErrorInvalidInput, version)
+	}
+	if chainId == nil || chainId.Sign() <= 0 {
+		return nil, errors.ErrorNetworkNotSupported
+	}
+	return &Safe{Address: common.HexToAddress(address).Hex(), Version: version, ChainId: chainId}, nil
+}
+
+// NewSafeOnNetwork returns a Safe on the chain of an evm coin on network
+func NewSafeOnNetwork(coin coins.Coin, network coins.Network, address string, version string) (*Safe, error) {
+	chainId, err := coins.EvmChainId(coin, network)
+	if err != nil {
+		return nil, err
+	}
+	return NewSafe(address, version, chainId)
+}
+
+// TypedData returns the EIP-712 typed data of tx, as owners see it in their wallets
+func (safe Safe) TypedData(tx Transaction) (*ethereum_signer.TypedData, error) {
+	for _, address := range []string{tx.To, tx.GasToken, tx.RefundReceiver} {
+		if address == "" {
+			continue
+		}
+		if err := coins.ValidateAddressOnChain(address, safe.ChainId); err != nil {
+			return nil, err
+		}
+	}
+	domain := ethereum_signer.TypedDataDomain{
+		ChainId:           (*math.HexOrDecimal256)(safe.ChainId),
+		VerifyingContract: safe.Address,
+	}
+	return &ethereum_signer.TypedData{
+		Types: ethereum_signer.Types{
+			"EIP712Domain": ethereum_signer.EIP712DomainTypes(domain),
+			"SafeTx":       safeTxType,
+		},
+		PrimaryType: "SafeTx",
+		Domain:      domain,
+		Message: ethereum_signer.TypedDataMessage{
+			"to":             addressOrZero(tx.To),
+			"value":          bigOrZero(tx.Value).String(),
+			"data":           hexutil.Encode(tx.Data),
+			"operation":      fmt.Sprint(uint8(tx.Operation)),
+			"safeTxGas":      bigOrZero(tx.SafeTxGas).String(),
+			"baseGas":        bigOrZero(tx.BaseGas).String(),
+			"gasPrice":       bigOrZero(tx.GasPrice).String(),
+			"gasToken":       addressOrZero(tx.GasToken),
+			"refundReceiver": addressOrZero(tx.RefundReceiver),
+			"nonce":          bigOrZero(tx.Nonce).String(),
+		},
+	}, nil
+}
+
+// TransactionHash returns the SafeTx hash the owners sign, getTransactionHash of the Safe
+func (safe Safe) TransactionHash(tx Transaction) (common.Hash, error) {
+	typedData, err := safe.TypedData(tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	rawData, err := ethereum_signer.EncodeForSigning(typedData)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(rawData), nil
+}
+
+// SignTransaction signs tx with the key of an owner
+func (safe Safe) SignTransaction(tx Transaction, privateKey types.PrivateKey, signatureType SignatureType) (*Signature, error) {
+	hash, err := safe.TransactionHash(tx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypto.ToECDSA(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	var signature []byte
+	switch signatureType {
+	case SignatureEIP712:
+		if signature, err = crypto.Sign(hash.Bytes(), key); err != nil {
+			return nil, err
+		}
+		signature[64] += 27
+	case SignatureEthSign:
+		if signature, err = crypto.Sign(ethSignHash(hash), key); err != nil {
+			return nil, err
+		}
+		signature[64] += 31
+	default:
+		return nil, fmt.Errorf("%w: unknown signature type %s", errors.ErrorInvalidInput, signatureType)
+	}
+	return &Signature{Owner: crypto.PubkeyToAddress(key.PublicKey).Hex(), Data: signature}, nil
+}
+
+// ApprovedHashSignature is the signature of an owner who approved the hash on chain with approveHash,
+// or who sends execTransaction itself
+func (safe Safe) ApprovedHashSignature(owner string) (*Signature, error) {
+	if err := coins.ValidateAddressOnChain(owner, safe.ChainId); err != nil {
+		return nil, err
+	}
+	data := make([]byte, 65)
+	copy(data[12:32], common.HexToAddress(owner).Bytes())
+	data[64] = 1
+	return &Signature{Owner: common.HexToAddress(owner).Hex(), Data: data}, nil
+}
+
+// RecoverOwner returns the owner that made an EIP-712 or eth_sign signature of tx
+func (safe Safe) RecoverOwner(tx Transaction, signature []byte) (string, error) {
+	if len(signature) != 65 {
+		return "", errors.ErrorInvalidSignature
+	}
+	hash, err := safe.TransactionHash(tx)
+	if err != nil {
+		return "", err
+	}
+	sig := common.CopyBytes(signature)
+	digest := hash.Bytes()
+	switch sig[64] {
+	case 27, 28:
+		sig[64] -= 27
+	case 31, 32:
+		sig[64] -= 31
+		digest = ethSignHash(hash)
+	default:
+		return "", fmt.Errorf("%w: v %d isn't an ecdsa signature", errors.ErrorInvalidSignature, sig[64])
+	}
+	publicKey, err := crypto.SigToPub(digest, sig)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errors.ErrorInvalidSignature, err)
+	}
+	return crypto.PubkeyToAddress(*publicKey).Hex(), nil
+}
+
+// EncodeSignatures checks the signatures of tx and joins them sorted by owner, as execTransaction takes them
+func (safe Safe) EncodeSignatures(tx Transaction, signatures []Signature) ([]byte, error) {
+	sorted := make([]Signature, len(signatures))
+	copy(sorted, signatures)
+	for _, signature := range sorted {
+		if coins.ValidateAddressOnChain(signature.Owner, safe.ChainId) != nil || len(signature.Data) != 65 {
+			return nil, errors.ErrorInvalidSignature
+		}
+		if signature.Data[64] == 0 || signature.Data[64] == 1 {
+			// contract signatures and approved hashes are checked by the Safe
+			continue
+		}
+		owner, err := safe.RecoverOwner(tx, signature.Data)
+		if err != nil {
+			return nil, err
+		}
+		if common.HexToAddress(owner) != common.HexToAddress(signature.Owner) {
+			return nil, fmt.Errorf("%w: signed by %s, not %s", errors.ErrorInvalidSignature, owner, signature.Owner)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(common.HexToAddress(sorted[i].Owner).Bytes(), common.HexToAddress(sorted[j].Owner).Bytes()) < 0
+	})
+	var encoded []byte
+	for i, signature := range sorted {
+		if i > 0 && common.HexToAddress(sorted[i-1].Owner) == common.HexToAddress(signature.Owner) {
+			return nil, fmt.Errorf("%w: %s signed twice", errors.ErrorInvalidSignature, signature.Owner)
+		}
+		encoded = append(encoded, signature.Data...)
+	}
+	return encoded, nil
+}
+
+// ExecTransactionData encodes the execTransaction call executing tx with the signatures of the owners
+func (safe Safe) ExecTransactionData(tx Transaction, signatures []Signature) ([]byte, error) {
+	encodedSignatures, err := safe.EncodeSignatures(tx, signatures)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := abi.JSON(strings.NewReader(execTransactionAbi))
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Pack("execTransaction", common.HexToAddress(tx.To), bigOrZero(tx.Value), []byte(tx.Data), uint8(tx.Operation),
+		bigOrZero(tx.SafeTxGas), bigOrZero(tx.BaseGas), bigOrZero(tx.GasPrice),
+		common.HexToAddress(tx.GasToken), common.HexToAddress(tx.RefundReceiver), encodedSignatures)
+}
+
+// ExecTransactionParams returns txParams calling execTransaction on the Safe, the nonce and gas of txParams
+// are those of the account sending the call, which doesn't have to be an owner
+func (safe Safe) ExecTransactionParams(tx Transaction, signatures []Signature, txParams coins.EthTxParams) (coins.EthTxParams, error) {
+	data, err := safe.ExecTransactionData(tx, signatures)
+	if err != nil {
+		return coins.EthTxParams{}, err
+	}
+	txParams.ToAddress = safe.Address
+	txParams.Amount = decimal.Zero
+	txParams.Data = hexutil.Encode(data)
+	return txParams, nil
+}
+
+func ethSignHash(hash common.Hash) []byte {
+	return crypto.Keccak256([]byte("\x19Ethereum Signed Message:\n32"), hash.Bytes())
+}
+
+func addressOrZero(address string) string {
+	if address == "" {
+		return common.Address{}.Hex()
+	}
+	return address
+}
+
+func bigOrZero(n *big.Int) *big.Int {
+	if n == nil {
+		return big.NewInt(0)
+	}
+	return n
+}
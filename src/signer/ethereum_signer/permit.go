@@ -0,0 +1,314 @@
+package ethereum_signer
+
+import (
+	"math/big"
+	"wallet-sdk/src/errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+// Permit2Address is the canonical Permit2 deployment, the same on every chain
+const Permit2Address = "0x000000000022D473030F116dDEE9F6B43aC78BA3"
+
+// Permit is an EIP-2612 permit
+type Permit struct {
+	Owner    string   `json:"owner"`
+	Spender  string   `json:"spender"`
+	Value    *big.Int `json:"value"`
+	Nonce    *big.Int `json:"nonce"`
+	Deadline *big.Int `json:"deadline"`
+}
+
+// DaiPermit is the permit of DAI and its forks, it approves all or nothing
+type DaiPermit struct {
+	Holder  string   `json:"holder"`
+	Spender string   `json:"spender"`
+	Nonce   *big.Int `json:"nonce"`
+	Expiry  *big.Int `json:"expiry"`
+	Allowed bool     `json:"allowed"`
+}
+
+// Permit2Details is the allowance of one token in Permit2 AllowanceTransfer permits
+type Permit2Details struct {
+	Token string `json:"token"`
+	// Amount is an uint160, Expiration and Nonce are uint48
+	Amount     *big.Int `json:"amount"`
+	Expiration *big.Int `json:"expiration"`
+	Nonce      *big.Int `json:"nonce"`
+}
+
+type Permit2Single struct {
+	Details     Permit2Details `json:"details"`
+	Spender     string         `json:"spender"`
+	SigDeadline *big.Int       `json:"sigDeadline"`
+}
+
+type Permit2Batch struct {
+	Details     []Permit2Details `json:"details"`
+	Spender     string           `json:"spender"`
+	SigDeadline *big.Int         `json:"sigDeadline"`
+}
+
+// Permit2TransferFrom is a Permit2 SignatureTransfer permit, its nonce is an unordered uint256
+type Permit2TransferFrom struct {
+	Token    string   `json:"token"`
+	Amount   *big.Int `json:"amount"`
+	Spender  string   `json:"spender"`
+	Nonce    *big.Int `json:"nonce"`
+	Deadline *big.Int `json:"deadline"`
+}
+
+// TransferAuthorization is an EIP-3009 authorization, Nonce is a random bytes32 hex string
+type TransferAuthorization struct {
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	Value       *big.Int `json:"value"`
+	ValidAfter  *big.Int `json:"validAfter"`
+	ValidBefore *big.Int `json:"validBefore"`
+	Nonce       string   `json:"nonce"`
+}
+
+// NewDomain returns an EIP-712 domain with name, version, chainId and verifyingContract, version is left out when empty
+func NewDomain(name string, version string, chainId int64, verifyingContract string) TypedDataDomain {
+	return TypedDataDomain{
+		Name:              name,
+		Version:           version,
+		ChainId:           math.NewHexOrDecimal256(chainId),
+		VerifyingContract: verifyingContract,
+	}
+}
+
+// NewPermitTypedData builds the typed data of an EIP-2612 permit, domain is the domain of the token
+func NewPermitTypedData(domain TypedDataDomain, permit Permit) (*TypedData, error) {
+	if err := validateAddresses(permit.Owner, permit.Spender); err != nil {
+		return nil, err
+	}
+	return &TypedData{
+		Types: Types{
+			"EIP712Domain": EIP712DomainTypes(domain),
+			"Permit": []Type{
+				{Name: "owner", Type: "address"},
+				{Name: "spender", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Permit",
+		Domain:      domain,
+		Message: TypedDataMessage{
+			"owner":    permit.Owner,
+			"spender":  permit.Spender,
+			"value":    bigString(permit.Value),
+			"nonce":    bigString(permit.Nonce),
+			"deadline": bigString(permit.Deadline),
+		},
+	}, nil
+}
+
+// NewDaiPermitTypedData builds the typed data of a DAI-style permit
+func NewDaiPermitTypedData(domain TypedDataDomain, permit DaiPermit) (*TypedData, error) {
+	if err := validateAddresses(permit.Holder, permit.Spender); err != nil {
+		return nil, err
+	}
+	return &TypedData{
+		Types: Types{
+			"EIP712Domain": EIP712DomainTypes(domain),
+			"Permit": []Type{
+				{Name: "holder", Type: "address"},
+				{Name: "spender", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "expiry", Type: "uint256"},
+				{Name: "allowed", Type: "bool"},
+			},
+		},
+		PrimaryType: "Permit",
+		Domain:      domain,
+		Message: TypedDataMessage{
+			"holder":  permit.Holder,
+			"spender": permit.Spender,
+			"nonce":   bigString(permit.Nonce),
+			"expiry":  bigString(permit.Expiry),
+			"allowed": permit.Allowed,
+		},
+	}, nil
+}
+
+// NewPermit2SingleTypedData builds the typed data of a Permit2 PermitSingle
+func NewPermit2SingleTypedData(chainId int64, permit Permit2Single) (*TypedData, error) {
+	if err := validateAddresses(permit.Details.Token, permit.Spender); err != nil {
+		return nil, err
+	}
+	domain := permit2Domain(chainId)
+	return &TypedData{
+		Types: Types{
+			"EIP712Domain":  EIP712DomainTypes(domain),
+			"PermitSingle":  permit2SingleType("PermitDetails"),
+			"PermitDetails": permit2DetailsType,
+		},
+		PrimaryType: "PermitSingle",
+		Domain:      domain,
+		Message: TypedDataMessage{
+			"details":     permit2DetailsMessage(permit.Details),
+			"spender":     permit.Spender,
+			"sigDeadline": bigString(permit.SigDeadline),
+		},
+	}, nil
+}
+
+// NewPermit2BatchTypedData builds the typed data of a Permit2 PermitBatch
+func NewPermit2BatchTypedData(chainId int64, permit Permit2Batch) (*TypedData, error) {
+	if len(permit.Details) == 0 {
+		return nil, errors.ErrorInvalidInput
+	}
+	if err := validateAddresses(permit.Spender); err != nil {
+		return nil, err
+	}
+	var details []interface{}
+	for _, detail := range permit.Details {
+		if err := validateAddresses(detail.Token); err != nil {
+			return nil, err
+		}
+		details = append(details, permit2DetailsMessage(detail))
+	}
+	domain := permit2Domain(chainId)
+	return &TypedData{
+		Types: Types{
+			"EIP712Domain":  EIP712DomainTypes(domain),
+			"PermitBatch":   permit2SingleType("PermitDetails[]"),
+			"PermitDetails": permit2DetailsType,
+		},
+		PrimaryType: "PermitBatch",
+		Domain:      domain,
+		Message: TypedDataMessage{
+			"details":     details,
+			"spender":     permit.Spender,
+			"sigDeadline": bigString(permit.SigDeadline),
+		},
+	}, nil
+}
+
+// NewPermit2TransferFromTypedData builds the typed data of a Permit2 PermitTransferFrom
+func NewPermit2TransferFromTypedData(chainId int64, permit Permit2TransferFrom) (*TypedData, error) {
+	if err := validateAddresses(permit.Token, permit.Spender); err != nil {
+		return nil, err
+	}
+	domain := permit2Domain(chainId)
+	return &TypedData{
+		Types: Types{
+			"EIP712Domain": EIP712DomainTypes(domain),
+			"PermitTransferFrom": []Type{
+				{Name: "permitted", Type: "TokenPermissions"},
+				{Name: "spender", Type: "address"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+			"TokenPermissions": []Type{
+				{Name: "token", Type: "address"},
+				{Name: "amount", Type: "uint256"},
+			},
+		},
+		PrimaryType: "PermitTransferFrom",
+		Domain:      domain,
+		Message: TypedDataMessage{
+			"permitted": map[string]interface{}{
+				"token":  permit.Token,
+				"amount": bigString(permit.Amount),
+			},
+			"spender":  permit.Spender,
+			"nonce":    bigString(permit.Nonce),
+			"deadline": bigString(permit.Deadline),
+		},
+	}, nil
+}
+
+// NewTransferWithAuthorizationTypedData builds the typed data of an EIP-3009 transferWithAuthorization
+func NewTransferWithAuthorizationTypedData(domain TypedDataDomain, authorization TransferAuthorization) (*TypedData, error) {
+	return newAuthorizationTypedData("TransferWithAuthorization", domain, authorization)
+}
+
+// NewReceiveWithAuthorizationTypedData builds the typed data of an EIP-3009 receiveWithAuthorization,
+// which only the payee can submit
+func NewReceiveWithAuthorizationTypedData(domain TypedDataDomain, authorization TransferAuthorization) (*TypedData, error) {
+	return newAuthorizationTypedData("ReceiveWithAuthorization", domain, authorization)
+}
+
+func newAuthorizationTypedData(primaryType string, domain TypedDataDomain, authorization TransferAuthorization) (*TypedData, error) {
+	if err := validateAddresses(authorization.From, authorization.To); err != nil {
+		return nil, err
+	}
+	nonce, err := hexutil.Decode(authorization.Nonce)
+	if err != nil || len(nonce) != common.HashLength {
+		return nil, errors.ErrorInvalidInput
+	}
+	return &TypedData{
+		Types: Types{
+			"EIP712Domain": EIP712DomainTypes(domain),
+			primaryType: []Type{
+				{Name: "from", Type: "address"},
+				{Name: "to", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "validAfter", Type: "uint256"},
+				{Name: "validBefore", Type: "uint256"},
+				{Name: "nonce", Type: "bytes32"},
+			},
+		},
+		PrimaryType: primaryType,
+		Domain:      domain,
+		Message: TypedDataMessage{
+			"from":        authorization.From,
+			"to":          authorization.To,
+			"value":       bigString(authorization.Value),
+			"validAfter":  bigString(authorization.ValidAfter),
+			"validBefore": bigString(authorization.ValidBefore),
+			"nonce":       authorization.Nonce,
+		},
+	}, nil
+}
+
+var permit2DetailsType = []Type{
+	{Name: "token", Type: "address"},
+	{Name: "amount", Type: "uint160"},
+	{Name: "expiration", Type: "uint48"},
+	{Name: "nonce", Type: "uint48"},
+}
+
+func permit2SingleType(detailsType string) []Type {
+	return []Type{
+		{Name: "details", Type: detailsType},
+		{Name: "spender", Type: "address"},
+		{Name: "sigDeadline", Type: "uint256"},
+	}
+}
+
+func permit2DetailsMessage(details Permit2Details) map[string]interface{} {
+	return map[string]interface{}{
+		"token":      details.Token,
+		"amount":     bigString(details.Amount),
+		"expiration": bigString(details.Expiration),
+		"nonce":      bigString(details.Nonce),
+	}
+}
+
+// permit2Domain has no version
+func permit2Domain(chainId int64) TypedDataDomain {
+	return NewDomain("Permit2", "", chainId, Permit2Address)
+}
+
+func validateAddresses(addresses ...string) error {
+	for _, address := range addresses {
+		if !common.IsHexAddress(address) {
+			return errors.ErrorInvalidAddress
+		}
+	}
+	return nil
+}
+
+func bigString(n *big.Int) string {
+	if n == nil {
+		return "0"
+	}
+	return n.String()
+}
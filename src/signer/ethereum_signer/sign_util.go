@@ -21,8 +21,6 @@ func SignPersonalMessage(message string, privateKey types.PrivateKey) (string, e
 //}
 
 func signMessage(message []byte, privateKey types.PrivateKey) (string, error) {
-	fmt.Println(hexutil.Encode(message))
-
 	key, err := crypto.ToECDSA(privateKey)
 	if err != nil {
 		log.Println("sign ToECDSA err:", err.Error())
@@ -37,6 +35,8 @@ func signMessage(message []byte, privateKey types.PrivateKey) (string, error) {
 	return hexutil.Encode(signatureByte), nil
 }
 
+// SignTypedData signs a hash computed by the caller, the typed data isn't checked.
+// Deprecated: use SignTypedDataJson or SignEIP712, which hash and check the typed data themselves.
 func SignTypedData(message []byte, privateKey types.PrivateKey) (string, error) {
 
 	key, err := crypto.ToECDSA(privateKey)
@@ -0,0 +1,337 @@
+package ethereum_signer
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"wallet-sdk/src/errors"
+	"wallet-sdk/src/types"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const siweHeader = " wants you to sign in with your Ethereum account:"
+
+const siweNonceChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// SiweMessage is a Sign-In with Ethereum (EIP-4361) message. Times are RFC 3339 strings,
+// they are kept as written so a parsed message prints back to the signed text.
+type SiweMessage struct {
+	// Scheme is the optional scheme of the origin, like https
+	Scheme string `json:"scheme,omitempty"`
+	Domain string `json:"domain"`
+	// Address is EIP-55 checksummed
+	Address        string   `json:"address"`
+	Statement      string   `json:"statement,omitempty"`
+	URI            string   `json:"uri"`
+	Version        string   `json:"version"`
+	ChainId        int64    `json:"chainId"`
+	Nonce          string   `json:"nonce"`
+	IssuedAt       string   `json:"issuedAt"`
+	ExpirationTime string   `json:"expirationTime,omitempty"`
+	NotBefore      string   `json:"notBefore,omitempty"`
+	RequestId      string   `json:"requestId,omitempty"`
+	Resources      []string `json:"resources,omitempty"`
+}
+
+// SiweVerifyOptions are the values the server expects, empty ones aren't checked
+type SiweVerifyOptions struct {
+	Domain  string
+	Nonce   string
+	ChainId int64
+	// Time is the time the message is checked at, now when zero
+	Time time.Time
+}
+
+// NewSiweMessage returns a version 1 message issued now with a random nonce
+func NewSiweMessage(domain string, address string, uri string, chainId int64, statement string) (*SiweMessage, error) {
+	nonce, err := NewSiweNonce()
+	if err != nil {
+		return nil, err
+	}
+	if !common.IsHexAddress(address) {
+		return nil, errors.ErrorInvalidAddress
+	}
+	message := &SiweMessage{
+		Domain:    domain,
+		Address:   common.HexToAddress(address).Hex(),
+		Statement: statement,
+		URI:       uri,
+		Version:   "1",
+		ChainId:   chainId,
+		Nonce:     nonce,
+		IssuedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+	if err = message.Validate(); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// NewSiweNonce returns a random 17 character alphanumeric nonce
+func NewSiweNonce() (string, error) {
+	nonce := make([]byte, 17)
+	limit := big.NewInt(int64(len(siweNonceChars)))
+	for i := range nonce {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		nonce[i] = siweNonceChars[n.Int64()]
+	}
+	return string(nonce), nil
+}
+
+// String returns the canonical text of the message, which is what gets signed
+func (message SiweMessage) String() string {
+	var builder strings.Builder
+	if message.Scheme != "" {
+		builder.WriteString(message.Scheme + "://")
+	}
+	builder.WriteString(message.Domain + siweHeader + "\n")
+	builder.WriteString(message.Address + "\n\n")
+	if message.Statement != "" {
+		builder.WriteString(message.Statement + "\n")
+	}
+	builder.WriteString("\n")
+	builder.WriteString("URI: " + message.URI + "\n")
+	builder.WriteString("Version: " + message.Version + "\n")
+	builder.WriteString("Chain ID: " + strconv.FormatInt(message.ChainId, 10) + "\n")
+	builder.WriteString("Nonce: " + message.Nonce + "\n")
+	builder.WriteString("Issued At: " + message.IssuedAt)
+	if message.ExpirationTime != "" {
+		builder.WriteString("\nExpiration Time: " + message.ExpirationTime)
+	}
+	if message.NotBefore != "" {
+		builder.WriteString("\nNot Before: " + message.NotBefore)
+	}
+	if message.RequestId != "" {
+		builder.WriteString("\nRequest ID: " + message.RequestId)
+	}
+	if len(message.Resources) > 0 {
+		builder.WriteString("\nResources:")
+		for _, resource := range message.Resources {
+			builder.WriteString("\n- " + resource)
+		}
+	}
+	return builder.String()
+}
+
+// Validate checks the syntax of every field
+func (message SiweMessage) Validate() error {
+	if message.Domain == "" || strings.ContainsAny(message.Domain, " /\n") {
+		return fmt.Errorf("%w: invalid domain %q", errors.ErrorInvalidInput, message.Domain)
+	}
+	if message.Scheme != "" && strings.ContainsAny(message.Scheme, ":/ \n") {
+		return fmt.Errorf("%w: invalid scheme %q", errors.ErrorInvalidInput, message.Scheme)
+	}
+	if !common.IsHexAddress(message.Address) || common.HexToAddress(message.Address).Hex() != message.Address {
+		return fmt.Errorf("%w: address must be EIP-55 checksummed", errors.ErrorInvalidAddress)
+	}
+	if strings.Contains(message.Statement, "\n") {
+		return fmt.Errorf("%w: statement must be a single line", errors.ErrorInvalidInput)
+	}
+	if err := validateSiweURI(message.URI); err != nil {
+		return err
+	}
+	if message.Version != "1" {
+		return fmt.Errorf("%w: unsupported version %q", errors.ErrorInvalidInput, message.Version)
+	}
+	if message.ChainId <= 0 {
+		return fmt.Errorf("%w: invalid chain id %d", errors.ErrorInvalidInput, message.ChainId)
+	}
+	if len(message.Nonce) < 8 || strings.Trim(message.Nonce, siweNonceChars) != "" {
+		return fmt.Errorf("%w: nonce must be at least 8 alphanumeric characters", errors.ErrorInvalidInput)
+	}
+	if _, err := time.Parse(time.RFC3339, message.IssuedAt); err != nil {
+		return fmt.Errorf("%w: issued at: %v", errors.ErrorInvalidInput, err)
+	}
+	if message.ExpirationTime != "" {
+		if _, err := time.Parse(time.RFC3339, message.ExpirationTime); err != nil {
+			return fmt.Errorf("%w: expiration time: %v", errors.ErrorInvalidInput, err)
+		}
+	}
+	if message.NotBefore != "" {
+		if _, err := time.Parse(time.RFC3339, message.NotBefore); err != nil {
+			return fmt.Errorf("%w: not before: %v", errors.ErrorInvalidInput, err)
+		}
+	}
+	if strings.Contains(message.RequestId, "\n") {
+		return fmt.Errorf("%w: request id must be a single line", errors.ErrorInvalidInput)
+	}
+	for _, resource := range message.Resources {
+		if err := validateSiweURI(resource); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckTime fails when the message is expired or not yet valid at now
+func (message SiweMessage) CheckTime(now time.Time) error {
+	if message.ExpirationTime != "" {
+		expirationTime, err := time.Parse(time.RFC3339, message.ExpirationTime)
+		if err != nil {
+			return err
+		}
+		if !now.Before(expirationTime) {
+			return errors.ErrorMessageExpired
+		}
+	}
+	if message.NotBefore != "" {
+		notBefore, err := time.Parse(time.RFC3339, message.NotBefore)
+		if err != nil {
+			return err
+		}
+		if now.Before(notBefore) {
+			return errors.ErrorMessageNotYetValid
+		}
+	}
+	return nil
+}
+
+// ParseSiweMessage parses and validates the text of an EIP-4361 message
+func ParseSiweMessage(text string) (*SiweMessage, error) {
+	lines := strings.Split(text, "\n")
+	invalid := fmt.Errorf("%w: not a sign-in with ethereum message", errors.ErrorInvalidInput)
+	if len(lines) < 9 || !strings.HasSuffix(lines[0], siweHeader) || lines[2] != "" {
+		return nil, invalid
+	}
+	message := SiweMessage{}
+	message.Domain = strings.TrimSuffix(lines[0], siweHeader)
+	if scheme, domain, found := strings.Cut(message.Domain, "://"); found {
+		message.Scheme, message.Domain = scheme, domain
+	}
+	message.Address = lines[1]
+
+	i := 3
+	if lines[i] != "" {
+		message.Statement = lines[i]
+		i++
+		if lines[i] != "" {
+			return nil, invalid
+		}
+	}
+	i++
+
+	field := func(name string, required bool) (string, error) {
+		if i < len(lines) && strings.HasPrefix(lines[i], name+": ") {
+			i++
+			return strings.TrimPrefix(lines[i-1], name+": "), nil
+		}
+		if required {
+			return "", fmt.Errorf("%w: missing %s", errors.ErrorInvalidInput, name)
+		}
+		return "", nil
+	}
+	var err error
+	if message.URI, err = field("URI", true); err != nil {
+		return nil, err
+	}
+	if message.Version, err = field("Version", true); err != nil {
+		return nil, err
+	}
+	chainId, err := field("Chain ID", true)
+	if err != nil {
+		return nil, err
+	}
+	if message.ChainId, err = strconv.ParseInt(chainId, 10, 64); err != nil {
+		return nil, fmt.Errorf("%w: invalid chain id %q", errors.ErrorInvalidInput, chainId)
+	}
+	if message.Nonce, err = field("Nonce", true); err != nil {
+		return nil, err
+	}
+	if message.IssuedAt, err = field("Issued At", true); err != nil {
+		return nil, err
+	}
+	message.ExpirationTime, _ = field("Expiration Time", false)
+	message.NotBefore, _ = field("Not Before", false)
+	message.RequestId, _ = field("Request ID", false)
+	if i < len(lines) && lines[i] == "Resources:" {
+		for i++; i < len(lines) && strings.HasPrefix(lines[i], "- "); i++ {
+			message.Resources = append(message.Resources, strings.TrimPrefix(lines[i], "- "))
+		}
+	}
+	if i != len(lines) {
+		return nil, fmt.Errorf("%w: unexpected line %q", errors.ErrorInvalidInput, lines[i])
+	}
+
+	if err = message.Validate(); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// SignSiweMessage signs message as a personal message, privateKey must be the key of message.Address
+func SignSiweMessage(message SiweMessage, privateKey types.PrivateKey) (string, error) {
+	if err := message.Validate(); err != nil {
+		return "", err
+	}
+	key, err := crypto.ToECDSA(privateKey)
+	if err != nil {
+		return "", err
+	}
+	if crypto.PubkeyToAddress(key.PublicKey) != common.HexToAddress(message.Address) {
+		return "", fmt.Errorf("%w: the key isn't the key of %s", errors.ErrorInvalidAddress, message.Address)
+	}
+	return SignPersonalMessage(message.String(), privateKey)
+}
+
+// VerifySiweMessage parses text and checks its signature, the values of options and the validity period.
+// The parsed message is returned when everything matches.
+func VerifySiweMessage(text string, signature string, options SiweVerifyOptions) (*SiweMessage, error) {
+	message, err := ParseSiweMessage(text)
+	if err != nil {
+		return nil, err
+	}
+	if options.Domain != "" && options.Domain != message.Domain {
+		return nil, fmt.Errorf("%w: expected %s, got %s", errors.ErrorDomainMismatch, options.Domain, message.Domain)
+	}
+	if options.Nonce != "" && options.Nonce != message.Nonce {
+		return nil, errors.ErrorNonceMismatch
+	}
+	if options.ChainId != 0 && options.ChainId != message.ChainId {
+		return nil, fmt.Errorf("%w: expected %d, got %d", errors.ErrorChainIdMismatch, options.ChainId, message.ChainId)
+	}
+	now := options.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if err = message.CheckTime(now); err != nil {
+		return nil, err
+	}
+
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != 65 {
+		return nil, errors.ErrorInvalidSignature
+	}
+	if sig[64] < 27 {
+		sig[64] += 27
+	}
+	publicKey, err := Recover(sig, []byte(text))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrorInvalidSignature, err)
+	}
+	address, err := NewEthereumAddress(*publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if common.BytesToAddress(address) != common.HexToAddress(message.Address) {
+		return nil, errors.ErrorInvalidSignature
+	}
+	return message, nil
+}
+
+func validateSiweURI(uri string) error {
+	parsed, err := url.Parse(uri)
+	if err != nil || !parsed.IsAbs() || strings.ContainsAny(uri, " \n") {
+		return fmt.Errorf("%w: invalid uri %q", errors.ErrorInvalidInput, uri)
+	}
+	return nil
+}
@@ -5,8 +5,15 @@
 package ethereum_signer
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/big"
+	"wallet-sdk/src/errors"
+	"wallet-sdk/src/types"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
 
@@ -33,7 +40,8 @@ func EncodeForSigning(typedData *TypedData) ([]byte, error) {
 	return rawData, nil
 }
 
-// EIP712DomainType is the type description for the EIP712 Domain
+// EIP712DomainType is the type description for the EIP712 Domain.
+// Deprecated: it only fits domains with exactly name, version and chainId, use EIP712DomainTypes.
 var EIP712DomainType = []Type{
 	{
 		Name: "name",
@@ -48,3 +56,191 @@ var EIP712DomainType = []Type{
 		Type: "uint256",
 	},
 }
+
+// EIP712DomainTypes returns the EIP712Domain type of the fields set in domain, in the canonical order
+func EIP712DomainTypes(domain TypedDataDomain) []Type {
+	var domainTypes []Type
+	if domain.Name != "" {
+		domainTypes = append(domainTypes, Type{Name: "name", Type: "string"})
+	}
+	if domain.Version != "" {
+		domainTypes = append(domainTypes, Type{Name: "version", Type: "string"})
+	}
+	if domain.ChainId != nil {
+		domainTypes = append(domainTypes, Type{Name: "chainId", Type: "uint256"})
+	}
+	if domain.VerifyingContract != "" {
+		domainTypes = append(domainTypes, Type{Name: "verifyingContract", Type: "address"})
+	}
+	if domain.Salt != "" {
+		domainTypes = append(domainTypes, Type{Name: "salt", Type: "bytes32"})
+	}
+	return domainTypes
+}
+
+// TypedDataSignature is a signature of typed data, split into v, r and s for contract calls
+type TypedDataSignature struct {
+	TypedData *TypedData `json:"typedData"`
+	Hash      string     `json:"hash"`
+	Signature string     `json:"signature"`
+	V         uint8      `json:"v"`
+	R         string     `json:"r"`
+	S         string     `json:"s"`
+}
+
+// SignEIP712 signs typed data, v of the signature is 27 or 28
+func SignEIP712(typedData *TypedData, privateKey types.PrivateKey) (*TypedDataSignature, error) {
+	rawData, err := EncodeForSigning(typedData)
+	if err != nil {
+		return nil, err
+	}
+	hash := crypto.Keccak256(rawData)
+	key, err := crypto.ToECDSA(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := crypto.Sign(hash, key)
+	if err != nil {
+		return nil, err
+	}
+	signature[64] += 27
+	return &TypedDataSignature{
+		TypedData: typedData,
+		Hash:      hexutil.Encode(hash),
+		Signature: hexutil.Encode(signature),
+		V:         signature[64],
+		R:         hexutil.Encode(signature[:32]),
+		S:         hexutil.Encode(signature[32:64]),
+	}, nil
+}
+
+// TypedDataPreview is what a wallet shows before signing typed data
+type TypedDataPreview struct {
+	PrimaryType     string           `json:"primaryType"`
+	Domain          TypedDataDomain  `json:"domain"`
+	Message         TypedDataMessage `json:"message"`
+	DomainSeparator string           `json:"domainSeparator"`
+	MessageHash     string           `json:"messageHash"`
+	Hash            string           `json:"hash"`
+}
+
+// ParseTypedDataJson parses the JSON of an eth_signTypedData_v4 request. The EIP712Domain type
+// is derived from the fields present in the domain when the request doesn't declare it.
+func ParseTypedDataJson(typedDataJson string) (*TypedData, error) {
+	var typedData TypedData
+	if err := json.Unmarshal([]byte(typedDataJson), &typedData); err != nil {
+		return nil, err
+	}
+	if typedData.Types == nil {
+		return nil, errors.ErrorInvalidInput
+	}
+	if _, ok := typedData.Types["EIP712Domain"]; !ok {
+		typedData.Types["EIP712Domain"] = EIP712DomainTypes(typedData.Domain)
+	}
+	if _, ok := typedData.Types[typedData.PrimaryType]; !ok || typedData.PrimaryType == "EIP712Domain" {
+		return nil, fmt.Errorf("%w: unknown primary type %q", errors.ErrorInvalidInput, typedData.PrimaryType)
+	}
+	return &typedData, nil
+}
+
+// PreviewTypedData hashes typed data without signing it
+func PreviewTypedData(typedData *TypedData) (*TypedDataPreview, error) {
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		return nil, err
+	}
+	messageHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return nil, err
+	}
+	rawData, err := EncodeForSigning(typedData)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedDataPreview{
+		PrimaryType:     typedData.PrimaryType,
+		Domain:          typedData.Domain,
+		Message:         typedData.Message,
+		DomainSeparator: domainSeparator.String(),
+		MessageHash:     messageHash.String(),
+		Hash:            hexutil.Encode(crypto.Keccak256(rawData)),
+	}, nil
+}
+
+// CheckTypedDataChainId fails when the domain of typed data names a chain other than chainId,
+// a domain without chainId is valid on every chain
+func CheckTypedDataChainId(typedData *TypedData, chainId *big.Int) error {
+	if typedData.Domain.ChainId == nil {
+		return nil
+	}
+	domainChainId := (*big.Int)(typedData.Domain.ChainId)
+	if chainId == nil || domainChainId.Cmp(chainId) != 0 {
+		return fmt.Errorf("%w: typed data is for chain %s, signing on chain %v", errors.ErrorChainIdMismatch, domainChainId, chainId)
+	}
+	return nil
+}
+
+// SignTypedDataJson signs an eth_signTypedData_v4 request for the chain chainId
+func SignTypedDataJson(typedDataJson string, chainId *big.Int, privateKey types.PrivateKey) (*TypedDataSignature, error) {
+	typedData, err := ParseTypedDataJson(typedDataJson)
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckTypedDataChainId(typedData, chainId); err != nil {
+		return nil, err
+	}
+	return SignEIP712(typedData, privateKey)
+}
+
+// VerifyTypedData returns the address that signed typed data, v of signature may be 0/1 or 27/28.
+// Contract wallets don't recover, check them with EIP1271IsValidSignatureData.
+func VerifyTypedData(typedData *TypedData, signature string) (string, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		return "", err
+	}
+	if len(sig) != 65 {
+		return "", ErrInvalidLength
+	}
+	if sig[64] < 27 {
+		sig[64] += 27
+	}
+	if sig[64] != 27 && sig[64] != 28 {
+		return "", fmt.Errorf("%w: invalid recovery id %d", errors.ErrorInvalidInput, sig[64])
+	}
+	publicKey, err := RecoverEIP712(sig, typedData)
+	if err != nil {
+		return "", err
+	}
+	address, err := NewEthereumAddress(*publicKey)
+	if err != nil {
+		return "", err
+	}
+	return common.BytesToAddress(address).Hex(), nil
+}
+
+// EIP1271MagicValue is returned by isValidSignature(bytes32,bytes) of a contract wallet accepting a signature,
+// it is also the selector of the method
+const EIP1271MagicValue = "0x1626ba7e"
+
+// EIP1271IsValidSignatureData encodes the isValidSignature(bytes32,bytes) call asking a contract wallet
+// whether signature is valid for hash
+func EIP1271IsValidSignatureData(hash []byte, signature []byte) ([]byte, error) {
+	if len(hash) != common.HashLength {
+		return nil, errors.ErrorInvalidInput
+	}
+	data := hexutil.MustDecode(EIP1271MagicValue)
+	data = append(data, hash...)
+	data = append(data, common.LeftPadBytes(big.NewInt(64).Bytes(), 32)...)
+	data = append(data, common.LeftPadBytes(big.NewInt(int64(len(signature))).Bytes(), 32)...)
+	data = append(data, signature...)
+	if padding := len(signature) % 32; padding != 0 {
+		data = append(data, make([]byte, 32-padding)...)
+	}
+	return data, nil
+}
+
+// IsEIP1271MagicValue reports whether the result of an isValidSignature call accepts the signature
+func IsEIP1271MagicValue(result []byte) bool {
+	return len(result) >= 4 && hexutil.Encode(result[:4]) == EIP1271MagicValue
+}
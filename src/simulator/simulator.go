@@ -0,0 +1,331 @@
+package simulator
+
+import (
+	"fmt"
+	"math/big"
+	"wallet-sdk/src/coins"
+	"wallet-sdk/src/errors"
+	types2 "wallet-sdk/src/types"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// event topics of the token transfers reported by a simulation
+var (
+	transferTopic       = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	transferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	transferBatchTopic  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+// EvmStateAccount is an account of a state snapshot, in the format of the prestateTracer of geth
+type EvmStateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// EvmBlockContext is the block a simulated transaction runs in
+type EvmBlockContext struct {
+	Number   uint64       `json:"number"`
+	Time     uint64       `json:"timestamp"`
+	GasLimit uint64       `json:"gasLimit"`
+	BaseFee  *hexutil.Big `json:"baseFeePerGas"`
+	Coinbase string       `json:"miner"`
+	// PrevRandao is the random value of post-merge blocks
+	PrevRandao common.Hash `json:"mixHash"`
+	// BlockHashes are the hashes BLOCKHASH returns, the zero hash for the blocks missing here
+	BlockHashes map[uint64]common.Hash `json:"blockHashes,omitempty"`
+}
+
+// EvmStateSnapshot is all the state a simulation reads, accounts missing from it are empty.
+// The sender must be in it with its nonce and a balance paying the gas.
+type EvmStateSnapshot struct {
+	Accounts map[common.Address]EvmStateAccount `json:"accounts"`
+	Block    EvmBlockContext                    `json:"block"`
+}
+
+// EvmSimulationResult is the outcome of a simulated transaction, amounts are in wei or token units
+type EvmSimulationResult struct {
+	Success         bool               `json:"success"`
+	Error           string             `json:"error,omitempty"`
+	RevertReason    string             `json:"revertReason,omitempty"`
+	ReturnData      string             `json:"returnData"`
+	GasUsed         uint64             `json:"gasUsed"`
+	ContractAddress string             `json:"contractAddress,omitempty"`
+	Logs            []EvmSimulatedLog  `json:"logs"`
+	TokenTransfers  []EvmTokenTransfer `json:"tokenTransfers"`
+	// BalanceDeltas are the changes of native balances, gas included
+	BalanceDeltas map[string]string `json:"balanceDeltas"`
+}
+
+type EvmSimulatedLog struct {
+	Address string   `json:"address"`
+	Topics  []string `json:"topics"`
+	Data    string   `json:"data"`
+}
+
+// EvmTokenTransfer is a token transfer event, Standard is ERC20, ERC721 or ERC1155
+type EvmTokenTransfer struct {
+	Standard string `json:"standard"`
+	Token    string `json:"token"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	TokenId  string `json:"tokenId,omitempty"`
+	Amount   string `json:"amount"`
+}
+
+// SimulateEvmTransaction runs an unsigned transaction sent by from against snapshot on the chain chainId.
+// It runs in process and offline, with the rules of a post-Shanghai chain.
+func SimulateEvmTransaction(baseTransaction *types2.BaseTransaction, from string, chainId *big.Int, snapshot EvmStateSnapshot) (*EvmSimulationResult, error) {
+	if err := coins.ValidateAddressOnChain(from, chainId); err != nil {
+		return nil, err
+	}
+	tx, ok := baseTransaction.CoinTransaction.(*types.Transaction)
+	if !ok {
+		return nil, errors.ErrorInvalidInput
+	}
+	return simulateEvmTx(tx, common.HexToAddress(from), chainId, snapshot)
+}
+
+// SimulateEvmRawTx runs a signed transaction against snapshot, see SimulateEvmTransaction
+func SimulateEvmRawTx(rawTx string, snapshot EvmStateSnapshot) (*EvmSimulationResult, error) {
+	tx, err := coins.DecodeTx(rawTx)
+	if err != nil {
+		return nil, err
+	}
+	if _, r, _ := tx.RawSignatureValues(); r == nil || r.Sign() == 0 {
+		return nil, errors.ErrorInvalidSignature
+	}
+	var signer types.Signer = types.HomesteadSigner{}
+	if tx.Protected() {
+		signer = types.LatestSignerForChainID(tx.ChainId())
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrorInvalidSignature, err)
+	}
+	return simulateEvmTx(tx, from, tx.ChainId(), snapshot)
+}
+
+func simulateEvmTx(tx *types.Transaction, from common.Address, chainId *big.Int, snapshot EvmStateSnapshot) (*EvmSimulationResult, error) {
+	if chainId == nil || chainId.Sign() <= 0 {
+		return nil, errors.ErrorNetworkNotSupported
+	}
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	if err != nil {
+		return nil, err
+	}
+	balancesBefore := make(map[common.Address]*big.Int, len(snapshot.Accounts))
+	for address, account := range snapshot.Accounts {
+		statedb.CreateAccount(address)
+		if account.Balance != nil {
+			statedb.SetBalance(address, account.Balance.ToInt())
+		}
+		statedb.SetNonce(address, account.Nonce)
+		statedb.SetCode(address, account.Code)
+		for key, value := range account.Storage {
+			statedb.SetState(address, key, value)
+		}
+		balancesBefore[address] = statedb.GetBalance(address)
+	}
+	statedb.Finalise(true)
+
+	block := snapshot.Block
+	gasLimit := block.GasLimit
+	if gasLimit == 0 {
+		gasLimit = 30000000
+	}
+	baseFee := new(big.Int)
+	if block.BaseFee != nil {
+		baseFee = block.BaseFee.ToInt()
+	}
+	coinbase := common.HexToAddress(block.Coinbase)
+	random := block.PrevRandao
+	blockContext := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash: func(n uint64) common.Hash {
+			return block.BlockHashes[n]
+		},
+		Coinbase:    coinbase,
+		GasLimit:    gasLimit,
+		BlockNumber: new(big.Int).SetUint64(block.Number),
+		Time:        block.Time,
+		Difficulty:  new(big.Int),
+		BaseFee:     baseFee,
+		Random:      &random,
+	}
+
+	msg := &core.Message{
+		To:         tx.To(),
+		From:       from,
+		Nonce:      tx.Nonce(),
+		Value:      tx.Value(),
+		GasLimit:   tx.Gas(),
+		GasPrice:   tx.GasPrice(),
+		GasFeeCap:  tx.GasFeeCap(),
+		GasTipCap:  tx.GasTipCap(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		msg.GasPrice = new(big.Int).Add(tx.GasTipCap(), baseFee)
+		if msg.GasPrice.Cmp(tx.GasFeeCap()) > 0 {
+			msg.GasPrice = tx.GasFeeCap()
+		}
+	}
+
+	tracer := &touchedAccountsTracer{accounts: map[common.Address]bool{from: true, coinbase: true}}
+	evm := vm.NewEVM(blockContext, core.NewEVMTxContext(msg), statedb, simulationChainConfig(chainId), vm.Config{Debug: true, Tracer: tracer})
+	statedb.SetTxContext(tx.Hash(), 0)
+	execution, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(gasLimit))
+	if err != nil {
+		// the transaction can't be included at all, like a nonce or balance error
+		return nil, fmt.Errorf("%w: %v", errors.ErrorInvalidInput, err)
+	}
+
+	result := EvmSimulationResult{
+		Success:        !execution.Failed(),
+		ReturnData:     hexutil.Encode(execution.ReturnData),
+		GasUsed:        execution.UsedGas,
+		Logs:           []EvmSimulatedLog{},
+		TokenTransfers: []EvmTokenTransfer{},
+		BalanceDeltas:  make(map[string]string),
+	}
+	if execution.Failed() {
+		result.Error = execution.Err.Error()
+		if reason, err := abi.UnpackRevert(execution.Revert()); err == nil {
+			result.RevertReason = reason
+		}
+	}
+	if tx.To() == nil && result.Success {
+		result.ContractAddress = crypto.CreateAddress(from, tx.Nonce()).Hex()
+	}
+	for _, log := range statedb.GetLogs(tx.Hash(), blockContext.BlockNumber.Uint64(), common.Hash{}) {
+		topics := make([]string, len(log.Topics))
+		for i, topic := range log.Topics {
+			topics[i] = topic.Hex()
+		}
+		result.Logs = append(result.Logs, EvmSimulatedLog{Address: log.Address.Hex(), Topics: topics, Data: hexutil.Encode(log.Data)})
+		result.TokenTransfers = append(result.TokenTransfers, decodeTokenTransfers(log)...)
+	}
+	for address := range snapshot.Accounts {
+		tracer.accounts[address] = true
+	}
+	for address := range tracer.accounts {
+		before, ok := balancesBefore[address]
+		if !ok {
+			before = new(big.Int)
+		}
+		delta := new(big.Int).Sub(statedb.GetBalance(address), before)
+		if delta.Sign() != 0 {
+			result.BalanceDeltas[address.Hex()] = delta.String()
+		}
+	}
+	return &result, nil
+}
+
+// decodeTokenTransfers decodes the ERC20, ERC721 and ERC1155 transfer events
+func decodeTokenTransfers(log *types.Log) []EvmTokenTransfer {
+	if len(log.Topics) == 0 {
+		return nil
+	}
+	address := func(topic common.Hash) string {
+		return common.BytesToAddress(topic.Bytes()).Hex()
+	}
+	token := log.Address.Hex()
+	switch {
+	case log.Topics[0] == transferTopic && len(log.Topics) == 3 && len(log.Data) == 32:
+		return []EvmTokenTransfer{{Standard: "ERC20", Token: token, From: address(log.Topics[1]), To: address(log.Topics[2]),
+			Amount: new(big.Int).SetBytes(log.Data).String()}}
+	case log.Topics[0] == transferTopic && len(log.Topics) == 4:
+		return []EvmTokenTransfer{{Standard: "ERC721", Token: token, From: address(log.Topics[1]), To: address(log.Topics[2]),
+			TokenId: log.Topics[3].Big().String(), Amount: "1"}}
+	case log.Topics[0] == transferSingleTopic && len(log.Topics) == 4 && len(log.Data) == 64:
+		return []EvmTokenTransfer{{Standard: "ERC1155", Token: token, From: address(log.Topics[2]), To: address(log.Topics[3]),
+			TokenId: new(big.Int).SetBytes(log.Data[:32]).String(), Amount: new(big.Int).SetBytes(log.Data[32:]).String()}}
+	case log.Topics[0] == transferBatchTopic && len(log.Topics) == 4:
+		uint256Array, _ := abi.NewType("uint256[]", "", nil)
+		arguments := abi.Arguments{{Type: uint256Array}, {Type: uint256Array}}
+		values, err := arguments.Unpack(log.Data)
+		if err != nil || len(values) != 2 {
+			return nil
+		}
+		ids, idsOk := values[0].([]*big.Int)
+		amounts, amountsOk := values[1].([]*big.Int)
+		if !idsOk || !amountsOk || len(ids) != len(amounts) {
+			return nil
+		}
+		var transfers []EvmTokenTransfer
+		for i := range ids {
+			transfers = append(transfers, EvmTokenTransfer{Standard: "ERC1155", Token: token, From: address(log.Topics[2]), To: address(log.Topics[3]),
+				TokenId: ids[i].String(), Amount: amounts[i].String()})
+		}
+		return transfers
+	}
+	return nil
+}
+
+// simulationChainConfig enables every fork up to Shanghai from genesis
+func simulationChainConfig(chainId *big.Int) *params.ChainConfig {
+	zero := uint64(0)
+	return &params.ChainConfig{
+		ChainID:                       chainId,
+		HomesteadBlock:                new(big.Int),
+		EIP150Block:                   new(big.Int),
+		EIP155Block:                   new(big.Int),
+		EIP158Block:                   new(big.Int),
+		ByzantiumBlock:                new(big.Int),
+		ConstantinopleBlock:           new(big.Int),
+		PetersburgBlock:               new(big.Int),
+		IstanbulBlock:                 new(big.Int),
+		MuirGlacierBlock:              new(big.Int),
+		BerlinBlock:                   new(big.Int),
+		LondonBlock:                   new(big.Int),
+		ArrowGlacierBlock:             new(big.Int),
+		GrayGlacierBlock:              new(big.Int),
+		MergeNetsplitBlock:            new(big.Int),
+		ShanghaiTime:                  &zero,
+		TerminalTotalDifficulty:       new(big.Int),
+		TerminalTotalDifficultyPassed: true,
+	}
+}
+
+// touchedAccountsTracer collects the accounts a transaction calls, which may receive value
+type touchedAccountsTracer struct {
+	accounts map[common.Address]bool
+}
+
+func (tracer *touchedAccountsTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (tracer *touchedAccountsTracer) CaptureTxEnd(restGas uint64) {}
+
+func (tracer *touchedAccountsTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	tracer.accounts[from] = true
+	tracer.accounts[to] = true
+}
+
+func (tracer *touchedAccountsTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {}
+
+func (tracer *touchedAccountsTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	tracer.accounts[from] = true
+	tracer.accounts[to] = true
+}
+
+func (tracer *touchedAccountsTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (tracer *touchedAccountsTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (tracer *touchedAccountsTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}